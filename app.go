@@ -2,11 +2,9 @@ package main
 
 import (
 	"context"
-	"crypto/md5"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"os"
 	"os/exec"
 	"path/filepath"
 	"rilaunch/pkg/appm"
@@ -35,12 +33,14 @@ type App struct {
 	notesStore   *notes.NotesStore
 	iconCache    map[string]string
 	iconMu       sync.RWMutex
+	recorder     *clipm.Recorder
 }
 
 func NewApp() *App {
 	return &App{
 		appManager: appm.NewManager(),
 		iconCache:  make(map[string]string),
+		recorder:   clipm.NewRecorder(),
 	}
 }
 
@@ -55,12 +55,37 @@ func (a *App) startup(ctx context.Context) {
 	clipm.SetRefreshCallback(func() {
 		wails_runtime.EventsEmit(ctx, "ClipboardUpdated")
 	})
-	go clipm.Record(ctx)
+	clipm.SetRecorderStateCallback(func(state clipm.RecorderState) {
+		wails_runtime.EventsEmit(ctx, "ClipRecorderStateChanged", state)
+	})
+	go a.recorder.Run(ctx)
+	go clipm.RunPruner(ctx)
+	go func() {
+		cm := &clipm.ClipM{DB: config.GetInstance().DB}
+		if rebuilt, err := cm.RebuildIndex(); err != nil {
+			fmt.Printf("Failed to index clipboard history: %v\n", err)
+		} else if rebuilt {
+			wails_runtime.EventsEmit(ctx, "ClipboardUpdated")
+		}
+		if _, err := cm.EncryptLegacySecrets(); err != nil {
+			fmt.Printf("Failed to encrypt secret clipboard entries: %v\n", err)
+		}
+	}()
 
+	appm.SetLaunchFailureCallback(func(report appm.LaunchReport) {
+		wails_runtime.EventsEmit(ctx, "AppLaunchFailed", report)
+	})
+	a.appManager.SetHistory(&appm.LaunchHistory{DB: config.GetInstance().DB})
 	go func() {
 		if err := a.appManager.Initialize(); err != nil {
 			fmt.Printf("Failed to initialize application manager: %v\n", err)
 		}
+		a.appManager.Watch(ctx, func() {
+			a.iconMu.Lock()
+			a.iconCache = make(map[string]string)
+			a.iconMu.Unlock()
+			wails_runtime.EventsEmit(ctx, "AppsUpdated")
+		})
 	}()
 
 	// Initialize file-based notes store
@@ -70,6 +95,7 @@ func (a *App) startup(ctx context.Context) {
 		fmt.Printf("Failed to init notes dir: %v\n", err)
 	}
 
+	go registerPauseHotkey(a)
 	a.RegisterHotKey()
 }
 
@@ -81,35 +107,139 @@ func (a *App) RegisterHotKey() {
 	registerHotkey(a)
 }
 
+// GetClipData returns the clipboard entries matching name as a JSON array,
+// pinned first, then newest first. name may hold app: and tag: terms.
+// QueryClipData returns the same entries a page at a time.
 func (a *App) GetClipData(name string) string {
+	cm := &clipm.ClipM{DB: config.GetInstance().DB}
+	entries := []clipm.ClipInfo{}
+	q := clipm.ClipQuery{Text: name}
+	for {
+		page, err := cm.Query(q)
+		if err != nil {
+			fmt.Println("Query", err)
+			return "[]"
+		}
+		entries = append(entries, page.Entries...)
+		if page.NextCursor == "" {
+			break
+		}
+		q.Cursor = page.NextCursor
+	}
+	jsonClipList, err := json.Marshal(entries)
+	if err != nil {
+		fmt.Println("Marshal", err)
+		return "[]"
+	}
+	return string(jsonClipList)
+}
 
-	clipDb := config.GetInstance()
+// QueryClipData returns a page of the clipboard history as JSON. query is a
+// JSON clipm.ClipQuery; pass the returned nextCursor as its cursor to get the
+// next page.
+func (a *App) QueryClipData(query string) string {
+	var q clipm.ClipQuery
+	if query != "" {
+		if err := json.Unmarshal([]byte(query), &q); err != nil {
+			return jsonError(err)
+		}
+	}
+	cm := &clipm.ClipM{DB: config.GetInstance().DB}
+	page, err := cm.Query(q)
+	if err != nil {
+		return jsonError(err)
+	}
+	data, err := json.Marshal(page)
+	if err != nil {
+		return jsonError(err)
+	}
+	return string(data)
+}
 
+// ToggleClipSecret marks an entry secret or not, and returns its new key:
+// secret entries are stored under a different key than plain ones.
+func (a *App) ToggleClipSecret(hash string) (string, error) {
+	clipDb := config.GetInstance()
 	clipm := &clipm.ClipM{
 		DB: clipDb.DB,
 	}
+	return clipm.MarkSecret(hash)
+}
+
+func (a *App) PinClip(hash string) error {
+	cm := &clipm.ClipM{DB: config.GetInstance().DB}
+	return cm.Pin(hash)
+}
+
+func (a *App) UnpinClip(hash string) error {
+	cm := &clipm.ClipM{DB: config.GetInstance().DB}
+	return cm.Unpin(hash)
+}
+
+func (a *App) AddClipTag(hash, tag string) error {
+	cm := &clipm.ClipM{DB: config.GetInstance().DB}
+	return cm.AddTag(hash, tag)
+}
+
+func (a *App) RemoveClipTag(hash, tag string) error {
+	cm := &clipm.ClipM{DB: config.GetInstance().DB}
+	return cm.RemoveTag(hash, tag)
+}
 
-	clipList, err := clipm.ReadAll()
+// CopyClip puts a clipboard history entry back on the OS clipboard. Images
+// are copied from the image bucket, text as-is.
+func (a *App) CopyClip(hash string) error {
+	cm := &clipm.ClipM{
+		DB: config.GetInstance().DB,
+	}
+	clipInfo, err := cm.Read(hash)
 	if err != nil {
-		fmt.Println("ReadAll", err)
-		return "[]"
+		return err
+	}
+	if clipInfo.IsSecret {
+		return fmt.Errorf("secret entries are copied with CopySecretClip")
+	}
+	if clipInfo.ContentType == clipm.ContentTypeImage {
+		data, err := cm.ReadImage(hash)
+		if err != nil {
+			return err
+		}
+		clipboard.Write(clipboard.FmtImage, data)
+		return nil
+	}
+	clipboard.Write(clipboard.FmtText, []byte(clipInfo.Content))
+	return nil
+}
+
+// CopySecretClip decrypts a secret entry and puts it on the OS clipboard.
+// It is the only way secret content leaves the database.
+func (a *App) CopySecretClip(hash string) error {
+	cm := &clipm.ClipM{
+		DB: config.GetInstance().DB,
 	}
-	clipm.SortByTimestamp(*clipList)
-	jsonClipList, err := json.Marshal(clipList)
+	content, err := cm.ReadSecret(hash)
 	if err != nil {
-		fmt.Println("Reverse", err)
+		return err
 	}
-	return string(jsonClipList)
+	clipboard.Write(clipboard.FmtText, []byte(content))
+	return nil
 }
 
-func (a *App) ToggleClipSecret(hash string) error {
+// GetClipThumbnail returns the thumbnail of an image entry as a data URL,
+// or "" if there is none.
+func (a *App) GetClipThumbnail(hash string) string {
 	clipDb := config.GetInstance()
 	clipm := &clipm.ClipM{
 		DB: clipDb.DB,
 	}
-	return clipm.MarkSecret(hash)
+	thumb, err := clipm.ReadThumbnail(hash)
+	if err != nil {
+		return ""
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(thumb)
 }
 
+// ClearClipboard deletes the clipboard history, except for pinned entries.
 func (a *App) ClearClipboard() error {
 	clipDb := config.GetInstance()
 	clipm := &clipm.ClipM{
@@ -125,6 +255,37 @@ func (a *App) ClearClipboard() error {
 	return err
 }
 
+// ── Clipboard Recording ───────────────────────────────────────────────────────
+
+// GetClipRecorderState returns whether clipboard recording is paused, and
+// until when, as JSON.
+func (a *App) GetClipRecorderState() string {
+	data, err := json.Marshal(a.recorder.State())
+	if err != nil {
+		return jsonError(err)
+	}
+	return string(data)
+}
+
+func (a *App) PauseClipRecording() error {
+	return a.recorder.Pause()
+}
+
+func (a *App) PauseClipRecordingFor(minutes int) error {
+	if minutes <= 0 {
+		return fmt.Errorf("invalid pause duration: %d minutes", minutes)
+	}
+	return a.recorder.PauseFor(time.Duration(minutes) * time.Minute)
+}
+
+func (a *App) ResumeClipRecording() error {
+	return a.recorder.Resume()
+}
+
+func (a *App) ToggleClipRecording() error {
+	return a.recorder.Toggle()
+}
+
 func (a *App) GetAllApps() string {
 	apps, err := a.appManager.GetAllApps()
 	if err != nil {
@@ -144,7 +305,13 @@ func (a *App) SearchApps(query string) string {
 }
 
 func (a *App) LaunchApp(appID string) error {
-	err := a.appManager.LaunchApp(appID)
+	return a.LaunchAppForQuery(appID, "")
+}
+
+// LaunchAppForQuery launches an app picked from the results of a search, so
+// the launch history learns which app the query refers to.
+func (a *App) LaunchAppForQuery(appID, query string) error {
+	err := a.appManager.LaunchApp(appID, query)
 	if err != nil {
 		fmt.Printf("LaunchApp error: %v\n", err)
 		return err
@@ -154,6 +321,140 @@ func (a *App) LaunchApp(appID string) error {
 	return nil
 }
 
+// LaunchAppWithArgs launches an app with the files, URLs or options typed
+// after its name, e.g. "gimp ~/shot.png".
+func (a *App) LaunchAppWithArgs(appID string, args []string, query string) error {
+	err := a.appManager.LaunchAppWithArgs(appID, args, query)
+	if err != nil {
+		fmt.Printf("LaunchAppWithArgs error: %v\n", err)
+		return err
+	}
+
+	a.hideWindow()
+	return nil
+}
+
+// CompleteLaunchQuery returns completions of the path being typed as the
+// last argument of the query, as whole queries.
+func (a *App) CompleteLaunchQuery(query string) []string {
+	return appm.CompleteLaunchQuery(query)
+}
+
+// GetOpenWithApps returns the MIME type of a typed path or URL and the apps
+// that can open it, the default first.
+func (a *App) GetOpenWithApps(target string) string {
+	result, err := a.appManager.OpenWith(target)
+	if err != nil {
+		fmt.Printf("GetOpenWithApps error: %v\n", err)
+		return jsonError(err)
+	}
+	return result
+}
+
+// OpenTarget opens a path or URL with its default app, as xdg-open would.
+func (a *App) OpenTarget(target string) error {
+	return a.OpenTargetWith(target, "")
+}
+
+// OpenTargetWith opens a path or URL with the chosen app.
+func (a *App) OpenTargetWith(target, appID string) error {
+	err := a.appManager.OpenTarget(target, appID)
+	if err != nil {
+		fmt.Printf("OpenTarget error: %v\n", err)
+		return err
+	}
+
+	a.hideWindow()
+	return nil
+}
+
+// LaunchAppAction launches a secondary action of an app, such as a browser's
+// "New Private Window".
+func (a *App) LaunchAppAction(appID, actionID string) error {
+	err := a.appManager.LaunchAppAction(appID, actionID, "")
+	if err != nil {
+		fmt.Printf("LaunchAppAction error: %v\n", err)
+		return err
+	}
+
+	a.hideWindow()
+	return nil
+}
+
+// GetLaunchLog returns the recent launches of an app, or of all apps if appID
+// is empty, newest first, with their exit status and early stderr output.
+func (a *App) GetLaunchLog(appID string) string {
+	data, err := json.Marshal(appm.LaunchReports(appID))
+	if err != nil {
+		return jsonError(err)
+	}
+	return string(data)
+}
+
+// ── Custom Entries ────────────────────────────────────────────────────────────
+
+// GetCustomEntries returns the custom entries file as JSON.
+func (a *App) GetCustomEntries() string {
+	c, err := appm.LoadCustomConfig()
+	if err != nil {
+		return jsonError(err)
+	}
+	data, _ := json.Marshal(c)
+	return string(data)
+}
+
+// SaveCustomEntry adds or updates a custom entry given as JSON and returns
+// the saved entry, with its ID filled in.
+func (a *App) SaveCustomEntry(entryJSON string) string {
+	var entry appm.CustomEntry
+	if err := json.Unmarshal([]byte(entryJSON), &entry); err != nil {
+		return `{"error":"invalid entry"}`
+	}
+	entry, err := appm.UpsertCustomEntry(entry)
+	if err != nil {
+		return jsonError(err)
+	}
+	a.customEntriesChanged()
+	data, _ := json.Marshal(entry)
+	return string(data)
+}
+
+func (a *App) DeleteCustomEntry(id string) error {
+	if err := appm.DeleteCustomEntry(id); err != nil {
+		return err
+	}
+	a.customEntriesChanged()
+	return nil
+}
+
+// SetAppAlias makes alias, e.g. "ff", find the app with the given ID or name.
+func (a *App) SetAppAlias(alias, target string) error {
+	if err := appm.SetAlias(alias, target); err != nil {
+		return err
+	}
+	a.customEntriesChanged()
+	return nil
+}
+
+func (a *App) DeleteAppAlias(alias string) error {
+	if err := appm.DeleteAlias(alias); err != nil {
+		return err
+	}
+	a.customEntriesChanged()
+	return nil
+}
+
+// jsonError encodes err the way the JSON-returning bindings report failures.
+func jsonError(err error) string {
+	data, _ := json.Marshal(map[string]string{"error": err.Error()})
+	return string(data)
+}
+
+func (a *App) customEntriesChanged() {
+	a.appManager.ReloadCustomEntries()
+	wails_runtime.EventsEmit(a.ctx, "AppsUpdated")
+}
+
 // interactiveCommands is the set of CLI programs that require a real TTY.
 // Running them in a non-TTY exec will hang or produce garbage output.
 var interactiveCommands = map[string]bool{
@@ -262,6 +563,18 @@ func (a *App) GetNotes() string {
 	return string(data)
 }
 
+// SearchNotes returns the notes fuzzy-matching query, with the matched
+// ranges of each note's title for highlighting.
+func (a *App) SearchNotes(query string) string {
+	matches, err := a.notesStore.Search(query)
+	if err != nil {
+		fmt.Printf("SearchNotes error: %v\n", err)
+		return "[]"
+	}
+	data, _ := json.Marshal(matches)
+	return string(data)
+}
+
 func (a *App) DeleteNote(id string) error {
 	return a.notesStore.Delete(id)
 }
@@ -298,72 +611,35 @@ func (a *App) ChooseNotesDir() string {
 
 // ── App Icons ─────────────────────────────────────────────────────────────────
 
-func (a *App) GetAppIcon(appPath string) string {
+// GetAppIcon returns the app's icon as a data URI, or "" if it has none.
+func (a *App) GetAppIcon(appID string) string {
 	// Fast path: check cache under read lock
 	a.iconMu.RLock()
-	cached, ok := a.iconCache[appPath]
+	cached, ok := a.iconCache[appID]
 	a.iconMu.RUnlock()
 	if ok {
 		return cached
 	}
 
-	// Slow path: extract icon, then store under write lock
-	icon := extractMacOSIconBase64(appPath)
-
-	a.iconMu.Lock()
-	a.iconCache[appPath] = icon
-	a.iconMu.Unlock()
-
-	return icon
-}
-
-func extractMacOSIconBase64(appPath string) string {
-	if goruntime.GOOS != "darwin" {
-		return ""
-	}
-
-	plistPath := filepath.Join(appPath, "Contents", "Info.plist")
-	if _, err := os.Stat(plistPath); err != nil {
+	app, ok := a.appManager.GetApp(appID)
+	if !ok {
 		return ""
 	}
 
-	out, err := exec.Command("plutil", "-extract", "CFBundleIconFile", "raw", "-o", "-", plistPath).Output()
-	iconName := strings.TrimSpace(string(out))
-	if err != nil || iconName == "" {
-		iconName = "AppIcon"
-	}
-	if !strings.HasSuffix(iconName, ".icns") {
-		iconName += ".icns"
-	}
-
-	icnsPath := filepath.Join(appPath, "Contents", "Resources", iconName)
-	if _, err := os.Stat(icnsPath); err != nil {
-		for _, name := range []string{"AppIcon.icns", "icon.icns", "Application.icns"} {
-			p := filepath.Join(appPath, "Contents", "Resources", name)
-			if _, err := os.Stat(p); err == nil {
-				icnsPath = p
-				break
-			}
-		}
-		if _, err := os.Stat(icnsPath); err != nil {
-			return ""
-		}
+	// Slow path: extract icon, then store under write lock
+	var icon string
+	switch goruntime.GOOS {
+	case "darwin":
+		icon = appm.MacOSIconDataURI(app.Path)
+	case "linux":
+		icon = appm.LinuxIconDataURI(app.Icon, 32)
 	}
 
-	// Use cached temp PNG if already converted
-	hash := md5.Sum([]byte(appPath))
-	tmpFile := filepath.Join(os.TempDir(), fmt.Sprintf("rilaunch_%x.png", hash))
-	if _, err := os.Stat(tmpFile); err != nil {
-		if err := exec.Command("sips", "-s", "format", "png", "-Z", "32", icnsPath, "--out", tmpFile).Run(); err != nil {
-			return ""
-		}
-	}
+	a.iconMu.Lock()
+	a.iconCache[appID] = icon
+	a.iconMu.Unlock()
 
-	data, err := os.ReadFile(tmpFile)
-	if err != nil {
-		return ""
-	}
-	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(data)
+	return icon
 }
 
 func registerHotkey(a *App) {
@@ -399,6 +675,21 @@ func registerHotkey(a *App) {
 	}
 }
 
+// registerPauseHotkey toggles clipboard recording with Ctrl+Shift+F12, so
+// it can be paused without opening the launcher, e.g. while screen-sharing.
+func registerPauseHotkey(a *App) {
+	hk := hotkey.New([]hotkey.Modifier{hotkey.ModCtrl, hotkey.ModShift}, hotkey.KeyF12)
+	if err := hk.Register(); err != nil {
+		fmt.Printf("Failed to register pause hotkey: %v\n", err)
+		return
+	}
+	for range hk.Keyup() {
+		if err := a.recorder.Toggle(); err != nil {
+			fmt.Printf("Failed to toggle clipboard recording: %v\n", err)
+		}
+	}
+}
+
 func (a *App) showWindow() {
 	a.isVisible = true
 	wails_runtime.EventsEmit(a.ctx, "Backend:GlobalHotkeyEvent", time.Now().String())
@@ -1,16 +1,31 @@
 import { createSignal, createEffect, createMemo, onMount, onCleanup, Show } from 'solid-js';
-import Fuse from 'fuse.js';
 import {
-  GetClipData,
+  QueryClipData,
   GetAllApps,
-  LaunchApp,
+  SearchApps,
+  SearchNotes,
+  LaunchAppForQuery,
+  LaunchAppAction,
+  LaunchAppWithArgs,
+  CompleteLaunchQuery,
+  GetOpenWithApps,
+  OpenTargetWith,
   ExecuteCommand,
   GetNotes,
   SaveNote,
   DeleteNote,
   UpdateNote,
   ToggleClipSecret,
-  ClearClipboard
+  PinClip,
+  UnpinClip,
+  AddClipTag,
+  RemoveClipTag,
+  CopyClip,
+  CopySecretClip,
+  ClearClipboard,
+  GetClipRecorderState,
+  PauseClipRecordingFor,
+  ToggleClipRecording
 } from '../wailsjs/go/main/App';
 import { EventsOn, WindowHide, WindowShow, Quit } from '../wailsjs/runtime/runtime';
 import SearchBar from './components/SearchBar';
@@ -42,15 +57,87 @@ function saveShellHistoryItem(cmd) {
 }
 
 
+// ── App list helpers ──────────────────────────────────────────────────────────
+
+// Longest common prefix of completions, so Tab completes as far as possible.
+function commonPrefix(items) {
+  return items.reduce((prefix, item) => {
+    let i = 0;
+    while (i < prefix.length && prefix[i] === item[i]) i++;
+    return prefix.slice(0, i);
+  });
+}
+
+// Flattens an app into its list item followed by one item per action.
+// actionMatches, when given, limits the actions to the ones that matched.
+function toAppItems(app, matches, actionMatches) {
+  const actions = (app.actions || []).filter(action => !actionMatches || actionMatches[action.id]);
+  return [
+    {
+      id: app.id,
+      title: app.displayName || app.name,
+      subtitle: app.args?.length ? `Open ${app.args.join(' ')}` : (app.description || 'Application'),
+      icon: app.icon || '',
+      category: app.category || 'App',
+      running: app.running,
+      matches: app.displayName ? matches : undefined,
+      appData: app,
+    },
+    ...actions.map(action => ({
+      id: `${app.id}#${action.id}`,
+      title: action.name,
+      subtitle: app.displayName || app.name,
+      icon: action.icon || app.icon || '',
+      category: 'Action',
+      actionId: action.id,
+      matches: actionMatches?.[action.id],
+      appData: app,
+    })),
+  ];
+}
+
+
+// A query that is a path or URL can be opened with the apps handling its type.
+function looksLikeTarget(q) {
+  return /^(~|\.{0,2}\/)/.test(q) || /^[a-z][a-z0-9+.-]+:\/\/\S/i.test(q) || /^(mailto|tel|magnet):\S/i.test(q);
+}
+
+// One "Open with" item per capable app, the default first. Without known
+// handlers the system opener decides, through a single "Open" item.
+function toOpenWithItems(result) {
+  if (result.error) return [];
+  if (!result.apps?.length) {
+    return [{
+      id: `open:${result.target}`,
+      title: `Open ${result.target}`,
+      subtitle: result.mimeType,
+      category: 'Open',
+      openTarget: result.target,
+    }];
+  }
+  return result.apps.map(app => ({
+    id: `open:${app.id}`,
+    title: `Open with ${app.displayName || app.name}`,
+    subtitle: app.id === result.default ? `${result.target} · default for ${result.mimeType}` : result.target,
+    icon: app.icon || '',
+    category: 'Open',
+    openTarget: result.target,
+    appData: app,
+  }));
+}
+
+
 // ── App ───────────────────────────────────────────────────────────────────────
 
 function App() {
   const TABS = ['apps', 'clipboard', 'notes', 'shell'];
+  const CLIP_PAGE_SIZE = 100;
 
   const [activeTab, setActiveTab] = createSignal('apps');
   const [searchQuery, setSearchQuery] = createSignal('');
   const [selectedIndex, setSelectedIndex] = createSignal(0);
   const [clipboardData, setClipboardData] = createSignal([]);
+  const [clipboardCursor, setClipboardCursor] = createSignal('');
   const [clipboardSelectedIndex, setClipboardSelectedIndex] = createSignal(0);
   const [allApps, setAllApps] = createSignal([]);
   const [commandOutput, setCommandOutput] = createSignal('');
@@ -68,43 +155,62 @@ function App() {
   let clipboardLoadId = 0;
   let notesLoadId = 0;
 
-  const showStatus = (msg, type = 'info') => {
+  const showStatus = (msg, type = 'info', duration = 2500) => {
     clearTimeout(statusTimer);
     setStatusMsg(msg);
     setStatusColor(type);
-    statusTimer = setTimeout(() => setStatusMsg(''), 2500);
+    statusTimer = setTimeout(() => setStatusMsg(''), duration);
   };
 
-  // ── Fuse index (rebuilt only when allApps changes) ────────────────────────
-  const fuseIndex = createMemo(() =>
-    new Fuse(allApps(), {
-      keys: ['title', 'subtitle', 'category'],
-      threshold: 0.4,
-      ignoreLocation: true,
-    })
-  );
-
   // ── Per-tab filtered data (memos) ─────────────────────────────────────────
+  // App search runs in Go, which returns fuzzy match ranges for highlighting
+  const [appSearchResults, setAppSearchResults] = createSignal([]);
+  let appSearchId = 0;
+
+  createEffect(() => {
+    const q = searchQuery().trim();
+    allApps();
+    if (activeTab() !== 'apps' || !q) return;
+    const requestId = ++appSearchId;
+    const openWith = looksLikeTarget(q)
+      ? GetOpenWithApps(q).then(raw => toOpenWithItems(JSON.parse(raw || '{}')))
+      : Promise.resolve([]);
+    Promise.all([openWith, SearchApps(q)])
+      .then(([openItems, raw]) => {
+        if (requestId !== appSearchId) return;
+        const results = JSON.parse(raw || '[]');
+        setAppSearchResults([...openItems, ...results.flatMap(r => toAppItems(r, r.matches, r.actionMatches || {}))]);
+      })
+      .catch(e => console.error('Failed to search apps:', e));
+  });
+
   const filteredApps = createMemo(() => {
     const q = searchQuery().trim();
-    if (!q) return allApps();
-    return fuseIndex().search(q).map(r => r.item);
+    if (!q) return allApps().filter(item => !item.actionId);
+    return appSearchResults();
   });
 
-  const filteredClipboardData = createMemo(() => {
-    const term = searchQuery().toLowerCase();
-    if (!term) return clipboardData();
-    return clipboardData().filter(item =>
-      (item.content || item.text || '').toLowerCase().includes(term)
-    );
+  // Notes are searched in Go as well, with match ranges in each note's title
+  const [noteSearchResults, setNoteSearchResults] = createSignal([]);
+  let noteSearchId = 0;
+
+  createEffect(() => {
+    const q = searchQuery().trim();
+    notesList();
+    if (activeTab() !== 'notes' || !q) return;
+    const requestId = ++noteSearchId;
+    SearchNotes(q)
+      .then(raw => {
+        if (requestId !== noteSearchId) return;
+        setNoteSearchResults(JSON.parse(raw || '[]'));
+      })
+      .catch(e => console.error('Failed to search notes:', e));
   });
 
   const filteredNotes = createMemo(() => {
-    const term = searchQuery().toLowerCase();
-    if (!term) return notesList();
-    return notesList().filter(n =>
-      n.content.toLowerCase().includes(term) || n.tag.toLowerCase().includes(term)
-    );
+    const q = searchQuery().trim();
+    if (!q) return notesList();
+    return noteSearchResults();
   });
 
   const shellSuggestion = createMemo(() => {
@@ -140,7 +246,6 @@ function App() {
       setShellHistoryIndex(-1);
     }
 
-    if (tab === 'clipboard') queueMicrotask(() => void loadClipboardData());
     if (tab === 'notes') queueMicrotask(() => void loadNotes());
 
     setTimeout(() => {
@@ -149,29 +254,47 @@ function App() {
   };
 
   // ── Data loaders ──────────────────────────────────────────────────────────
-  const loadClipboardData = async () => {
+  const queryClipboard = async (query) => {
+    const page = JSON.parse(await QueryClipData(JSON.stringify(query)) || '{}');
+    if (page.error) throw new Error(page.error);
+    return page;
+  };
+
+  // Loads the first page of entries matching the search
+  const loadClipboardData = async (text = searchQuery().trim()) => {
     const requestId = ++clipboardLoadId;
     try {
-      const raw = await GetClipData('');
+      const page = await queryClipboard({ text, limit: CLIP_PAGE_SIZE });
       if (requestId !== clipboardLoadId) return;
-      setClipboardData(JSON.parse(raw || '[]'));
+      setClipboardData(page.entries || []);
+      setClipboardCursor(page.nextCursor || '');
     } catch (e) {
       console.error('Failed to load clipboard:', e);
     }
   };
 
+  // Appends the next page, if there is one
+  const loadMoreClipboardData = async () => {
+    const cursor = clipboardCursor();
+    if (!cursor) return;
+    setClipboardCursor('');
+    const requestId = clipboardLoadId;
+    try {
+      const page = await queryClipboard({ text: searchQuery().trim(), cursor, limit: CLIP_PAGE_SIZE });
+      if (requestId !== clipboardLoadId) return;
+      setClipboardData(data => [...data, ...(page.entries || [])]);
+      setClipboardCursor(page.nextCursor || '');
+    } catch (e) {
+      console.error('Failed to load clipboard:', e);
+      setClipboardCursor(cursor);
+    }
+  };
+
   const loadAllApps = async () => {
     try {
       const raw = await GetAllApps();
       const parsed = JSON.parse(raw || '[]');
-      const mapped = parsed.map(app => ({
-        id: app.id,
-        title: app.displayName || app.name,
-        subtitle: app.description || 'Application',
-        icon: app.icon || '',
-        category: app.category || 'App',
-        appData: app,
-      }));
+      const mapped = parsed.flatMap(app => toAppItems(app));
       setAllApps(mapped);
       if (mapped.length === 0) setTimeout(loadAllApps, 1500);
     } catch (e) {
@@ -193,9 +316,24 @@ function App() {
 
   // ── Actions ───────────────────────────────────────────────────────────────
   const handleAppLaunch = async (command) => {
+    if (command?.openTarget) {
+      try {
+        await OpenTargetWith(command.openTarget, command.appData?.id || '');
+      } catch (e) {
+        showStatus(`Failed to open: ${e}`, 'error');
+      }
+      return;
+    }
     if (command?.appData) {
       try {
-        await LaunchApp(command.appData.id);
+        if (command.actionId) {
+          await LaunchAppAction(command.appData.id, command.actionId);
+        } else if (command.appData.args?.length) {
+          await LaunchAppWithArgs(command.appData.id, command.appData.args, searchQuery().trim());
+        } else {
+          await LaunchAppForQuery(command.appData.id, searchQuery().trim());
+        }
+        void loadAllApps();
       } catch (e) {
         console.error(e);
       }
@@ -214,25 +352,67 @@ function App() {
     }
   };
 
-  const handleClipboardItemClick = (item) => {
-    navigator.clipboard.writeText(item.content || item.text || '');
-    WindowHide();
+  // Copying goes through Go, which can put images back on the clipboard too
+  const handleClipboardItemClick = async (item) => {
+    try {
+      await (item.is_secret ? CopySecretClip(item.hash) : CopyClip(item.hash));
+      WindowHide();
+    } catch (e) {
+      showStatus('Failed to copy', 'error');
+    }
   };
 
   const handleToggleSecret = async (item) => {
     try {
-      await ToggleClipSecret(item.hash);
-      setClipboardData(prev => prev.map(x => x.hash === item.hash ? { ...x, is_secret: !x.is_secret } : x));
+      // Toggling re-encrypts the entry under a new key, so reload the list
+      // and keep the entry selected under that key
+      const key = await ToggleClipSecret(item.hash);
+      await loadClipboardData();
+      const index = clipboardData().findIndex(entry => entry.hash === key);
+      if (index >= 0) setClipboardSelectedIndex(index);
     } catch (e) {
       console.error('Failed to toggle secret:', e);
+      showStatus(`${e}`, 'error');
+    }
+  };
+
+  const handleTogglePin = async (item) => {
+    try {
+      await (item.pinned ? UnpinClip(item.hash) : PinClip(item.hash));
+      await loadClipboardData();
+    } catch (e) {
+      console.error('Failed to toggle pin:', e);
+      showStatus(`${e}`, 'error');
+    }
+  };
+
+  const handleAddTag = async (item) => {
+    const tag = prompt('Tag:')?.trim();
+    if (!tag) return;
+    try {
+      await AddClipTag(item.hash, tag);
+      await loadClipboardData();
+    } catch (e) {
+      console.error('Failed to add tag:', e);
+      showStatus(`${e}`, 'error');
+    }
+  };
+
+  const handleRemoveTag = async (item, tag) => {
+    try {
+      await RemoveClipTag(item.hash, tag);
+      await loadClipboardData();
+    } catch (e) {
+      console.error('Failed to remove tag:', e);
+      showStatus(`${e}`, 'error');
     }
   };
 
   const handleClearClipboard = async () => {
-    if (confirm('Are you sure you want to clear all clipboard items?')) {
+    if (confirm('Clear all clipboard items? Pinned items are kept.')) {
       try {
         await ClearClipboard();
-        setClipboardData([]);
+        await loadClipboardData();
         showStatus('Clipboard cleared', 'success');
       } catch (e) {
         console.error(e);
@@ -241,6 +421,35 @@ function App() {
     }
   };
 
+  // ── Clipboard recording ───────────────────────────────────────────────────
+  const [recorderState, setRecorderState] = createSignal({ paused: false });
+
+  const loadRecorderState = async () => {
+    try {
+      setRecorderState(JSON.parse(await GetClipRecorderState()));
+    } catch (e) {
+      console.error(e);
+    }
+  };
+
+  const handleToggleRecording = async () => {
+    try {
+      await ToggleClipRecording();
+    } catch (e) {
+      console.error(e);
+      showStatus('Failed to change clipboard recording', 'error');
+    }
+  };
+
+  const handlePauseRecordingFor = async (minutes) => {
+    try {
+      await PauseClipRecordingFor(minutes);
+    } catch (e) {
+      console.error(e);
+      showStatus('Failed to pause clipboard recording', 'error');
+    }
+  };
+
   const handleClearConsole = () => {
     setCommandOutput('');
     showStatus('Console cleared', 'success');
@@ -310,6 +519,13 @@ function App() {
       return;
     }
 
+    // Ctrl/Cmd + Shift + P: pause or resume clipboard recording
+    if ((e.metaKey || e.ctrlKey) && e.shiftKey && e.key.toLowerCase() === 'p') {
+      e.preventDefault();
+      void handleToggleRecording();
+      return;
+    }
+
     // Ctrl/Cmd + 1..4: direct switch
     if ((e.metaKey || e.ctrlKey) && ['1', '2', '3', '4'].includes(e.key)) {
       e.preventDefault();
@@ -365,20 +581,21 @@ function App() {
 
     // Clipboard tab
     if (activeTab() === 'clipboard') {
-      const data = filteredClipboardData();
+      const data = clipboardData();
       if (e.key === 'ArrowDown') {
         e.preventDefault();
-        setClipboardSelectedIndex(i => (i + 1) % Math.max(data.length, 1));
+        // Load the next page before the end, and only wrap around on the last
+        const next = clipboardSelectedIndex() + 1;
+        const hasMore = clipboardCursor() !== '';
+        if (hasMore && next >= data.length - 1) void loadMoreClipboardData();
+        setClipboardSelectedIndex(next < data.length ? next : hasMore ? data.length - 1 : 0);
       } else if (e.key === 'ArrowUp') {
         e.preventDefault();
         setClipboardSelectedIndex(i => i === 0 ? data.length - 1 : i - 1);
       } else if (e.key === 'Enter') {
         e.preventDefault();
         const item = data[clipboardSelectedIndex()];
-        if (item) {
-          navigator.clipboard.writeText(item.content || item.text || '');
-          WindowHide();
-        }
+        if (item) void handleClipboardItemClick(item);
       }
       return;
     }
@@ -394,10 +611,21 @@ function App() {
     } else if (e.key === 'Enter') {
       e.preventDefault();
       await handleAppLaunch(filtered[selectedIndex()]);
+    } else if (e.key === 'Tab' && /\s/.test(searchQuery().trim())) {
+      // Complete the path typed after the app name, e.g. "code ~/sr"
+      e.preventDefault();
+      const completions = await CompleteLaunchQuery(searchQuery());
+      if (completions?.length) setSearchQuery(commonPrefix(completions));
     }
   };
 
   // ── Effects ───────────────────────────────────────────────────────────────
+  // Clipboard search runs in Go, a page at a time; "app:<name>" and
+  // "tag:<tag>" terms in the query filter by source app and tag
+  createEffect(() => {
+    if (activeTab() === 'clipboard') void loadClipboardData(searchQuery().trim());
+  });
+
   createEffect(() => {
     searchQuery();
     setSelectedIndex(0);
@@ -410,7 +638,19 @@ function App() {
     EventsOn('ClipboardUpdated', () => {
       if (activeTab() === 'clipboard') void loadClipboardData();
     });
+    EventsOn('AppsUpdated', () => void loadAllApps());
+    EventsOn('ClipRecorderStateChanged', (state) => {
+      setRecorderState(state);
+      showStatus(state.paused ? 'Clipboard recording paused' : 'Clipboard recording resumed', 'success');
+    });
+    // The window hides on launch, so bring it back to report a failed one
+    EventsOn('AppLaunchFailed', (report) => {
+      const detail = report.stderr?.trim().split('\n').pop() || report.error;
+      WindowShow();
+      showStatus(`${report.name} failed to start: ${detail}`, 'error', 8000);
+    });
     void loadAllApps();
+    void loadRecorderState();
     searchInputRef?.focus();
   });
 
@@ -459,6 +699,18 @@ function App() {
                 </Show>
 
                 <Show when={activeTab() === 'clipboard'}>
+                  <button class="menu-item" onClick={() => { setIsMenuOpen(false); void handleToggleRecording(); }}>
+                    <IconClipboard />
+                    <span>{recorderState().paused ? 'Resume Recording' : 'Pause Recording'}</span>
+                  </button>
+                  <button class="menu-item" onClick={() => { setIsMenuOpen(false); void handlePauseRecordingFor(15); }}>
+                    <IconClipboard />
+                    <span>Pause for 15 Minutes</span>
+                  </button>
+                  <button class="menu-item" onClick={() => { setIsMenuOpen(false); void handlePauseRecordingFor(60); }}>
+                    <IconClipboard />
+                    <span>Pause for 1 Hour</span>
+                  </button>
                   <button class="menu-item danger" onClick={() => { setIsMenuOpen(false); void handleClearClipboard(); }}>
                     <IconTrash />
                     <span>Clear All</span>
@@ -557,10 +809,16 @@ function App() {
             <Show when={!showSettings() && activeTab() === 'clipboard'}>
               <ClipboardView
                 clipboardData={clipboardData()}
-                filteredClipboardData={filteredClipboardData()}
+                isFiltered={searchQuery().trim() !== ''}
+                onLoadMore={loadMoreClipboardData}
                 clipboardSelectedIndex={clipboardSelectedIndex()}
                 onItemClick={handleClipboardItemClick}
                 onToggleSecret={handleToggleSecret}
+                onTogglePin={handleTogglePin}
+                onAddTag={handleAddTag}
+                onRemoveTag={handleRemoveTag}
+                recorderState={recorderState()}
+                onResumeRecording={handleToggleRecording}
               />
             </Show>
 
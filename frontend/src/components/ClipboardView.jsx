@@ -1,6 +1,17 @@
-import { For, Show } from 'solid-js';
+import { For, Show, createResource } from 'solid-js';
+import { GetClipThumbnail } from '../../wailsjs/go/main/App';
 import './ClipboardView.css';
 
+// Lazy-loading thumbnail of an image entry
+function ClipThumbnail({ hash }) {
+  const [src] = createResource(() => hash, GetClipThumbnail);
+  return (
+    <Show when={src()}>
+      <img src={src()} class="clip-thumbnail" alt="" />
+    </Show>
+  );
+}
+
 // --- SVGs for Eye and Eye-Slash Icons ---
 const IconEye = () => (
   <svg class="eye-icon" width="13" height="13" viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="2.2" stroke-linecap="round" stroke-linejoin="round">
@@ -16,22 +27,85 @@ const IconEyeSlash = () => (
   </svg>
 );
 
+const IconPin = (props) => (
+  <svg class="pin-icon" width="13" height="13" viewBox="0 0 24 24" fill={props.filled ? 'currentColor' : 'none'} stroke="currentColor" stroke-width="2.2" stroke-linecap="round" stroke-linejoin="round">
+    <path d="M12 17v5M9 3h6l-1 7 4 4H6l4-4-1-7z" />
+  </svg>
+);
+
 function ClipboardView(props) {
   return (
     <div class="clipboard-view">
-      <div class="clipboard-list">
-        <For each={props.filteredClipboardData}>
+      <Show when={props.recorderState?.paused}>
+        <div class="clip-paused">
+          <span>
+            Recording paused
+            {props.recorderState.pausedUntil
+              ? ` until ${new Date(props.recorderState.pausedUntil).toLocaleTimeString()}`
+              : ''}
+          </span>
+          <button class="clip-resume-btn" onClick={() => props.onResumeRecording()}>Resume</button>
+        </div>
+      </Show>
+      <div
+        class="clipboard-list"
+        onScroll={(e) => {
+          const el = e.currentTarget;
+          if (el.scrollTop + el.clientHeight >= el.scrollHeight - 200) props.onLoadMore();
+        }}
+      >
+        <For each={props.clipboardData}>
           {(item, index) => (
             <div
-              class={`clipboard-item${index() === props.clipboardSelectedIndex ? ' selected' : ''}`}
+              class={`clipboard-item${index() === props.clipboardSelectedIndex ? ' selected' : ''}${item.pinned ? ' pinned' : ''}`}
               onClick={() => props.onItemClick(item)}
             >
+              <Show when={item.content_type === 'image'}>
+                <ClipThumbnail hash={item.hash} />
+              </Show>
               <div class={`clip-text${item.is_secret ? ' masked' : ''}`}>
                 {item.content || item.text || 'No content'}
               </div>
               <div class="clip-meta">
-                <span class="clip-type">{item.type || 'text'}</span>
+                <span class="clip-type">
+                  {item.secret_reason || item.content_type || 'text'}
+                  <Show when={item.application}>{` · ${item.application}`}</Show>
+                </span>
                 <div class="clip-meta-right">
+                  <For each={item.tag || []}>
+                    {(tag) => (
+                      <span
+                        class="clip-tag"
+                        title="Remove tag"
+                        onClick={(e) => {
+                          e.stopPropagation();
+                          props.onRemoveTag(item, tag);
+                        }}
+                      >
+                        #{tag}
+                      </span>
+                    )}
+                  </For>
+                  <button
+                    class="clip-mask-btn"
+                    onClick={(e) => {
+                      e.stopPropagation();
+                      props.onAddTag(item);
+                    }}
+                    title="Add tag"
+                  >
+                    #
+                  </button>
+                  <button
+                    class="clip-mask-btn clip-pin-btn"
+                    onClick={(e) => {
+                      e.stopPropagation();
+                      props.onTogglePin(item);
+                    }}
+                    title={item.pinned ? 'Unpin' : 'Pin to top'}
+                  >
+                    <IconPin filled={item.pinned} />
+                  </button>
                   <button
                     class="clip-mask-btn"
                     onClick={(e) => {
@@ -44,17 +118,25 @@ function ClipboardView(props) {
                       <IconEyeSlash />
                     </Show>
                   </button>
+                  <Show when={item.copy_count > 1}>
+                    <span
+                      class="clip-count"
+                      title={item.first_seen ? `First copied ${new Date(item.first_seen).toLocaleString()}` : ''}
+                    >
+                      copied {item.copy_count} times
+                    </span>
+                  </Show>
                   <span class="clip-time">
-                    {item.timestamp ? new Date(item.timestamp * 1000).toLocaleTimeString() : ''}
+                    {item.timestamp ? new Date(item.timestamp).toLocaleTimeString() : ''}
                   </span>
                 </div>
               </div>
             </div>
           )}
         </For>
-        <Show when={props.filteredClipboardData.length === 0}>
+        <Show when={props.clipboardData.length === 0}>
           <div class="clip-empty">
-            {props.clipboardData.length > 0 ? 'No matching items' : 'Clipboard is empty'}
+            {props.isFiltered ? 'No matching items' : 'Clipboard is empty'}
           </div>
         </Show>
       </div>
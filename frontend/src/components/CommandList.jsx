@@ -13,10 +13,10 @@ function nameToColor(str) {
 }
 
 // Lazy-loading app icon with letter avatar fallback
-function AppIcon({ appPath, name }) {
+function AppIcon({ appId, name }) {
   const [iconSrc] = createResource(
-    () => appPath,
-    (path) => path ? GetAppIcon(path) : Promise.resolve('')
+    () => appId,
+    (id) => id ? GetAppIcon(id) : Promise.resolve('')
   );
 
   return (
@@ -36,6 +36,22 @@ function AppIcon({ appPath, name }) {
   );
 }
 
+// Splits text into plain and matched parts. Ranges are character offsets
+// as returned by the Go fuzzy matcher.
+export function Highlighted({ text, ranges }) {
+  if (!ranges || ranges.length === 0) return text;
+  const chars = Array.from(text);
+  const parts = [];
+  let pos = 0;
+  for (const { start, end } of ranges) {
+    if (start > pos) parts.push(chars.slice(pos, start).join(''));
+    parts.push(<span class="command-match">{chars.slice(start, end).join('')}</span>);
+    pos = end;
+  }
+  if (pos < chars.length) parts.push(chars.slice(pos).join(''));
+  return parts;
+}
+
 function CommandList(props) {
   const handleClick = (command, index) => {
     props.onSelect(index);
@@ -54,15 +70,22 @@ function CommandList(props) {
             >
               <div class="command-icon">
                 <AppIcon
-                  appPath={command.appData?.path || command.appData?.Path || ''}
+                  appId={command.appData?.id || ''}
                   name={command.title}
                 />
               </div>
               <div class="command-content">
-                <div class="command-title">{command.title}</div>
+                <div class="command-title">
+                  <Highlighted text={command.title} ranges={command.matches} />
+                </div>
                 <div class="command-subtitle">{command.subtitle}</div>
               </div>
-              <div class="command-category">{command.category}</div>
+              <div class="command-category">
+                <Show when={command.running}>
+                  <span class="command-running" title="Running" />
+                </Show>
+                {command.category}
+              </div>
             </div>
           )}
         </For>
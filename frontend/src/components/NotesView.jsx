@@ -1,5 +1,6 @@
 import { createSignal, createMemo, For, Show } from "solid-js";
 import { UpdateNote } from "../../wailsjs/go/main/App";
+import { Highlighted } from "./CommandList";
 import "./NotesView.css";
 import { marked } from "marked";
 
@@ -141,15 +142,20 @@ function NotesView(props) {
             {(note) => {
               const preview =
                 (note.content || "").split("\n").find((l) => l.trim()) || "";
-              const clean = preview
-                .replace(/^#+\s*/, "")
-                .replace(/[*_`]/g, "")
-                .slice(0, 72);
+              const clean =
+                note.title ??
+                preview
+                  .replace(/^#+\s*/, "")
+                  .replace(/[*_`]/g, "")
+                  .slice(0, 72);
               const fileId = note.id.split("_").join(" ");
               return (
                 <div class="note-row" onClick={() => openPreview(note)}>
                   <div class="note-row-fileid">{` ${fileId}` || "(empty)"}</div>
-                  <div class="note-row-preview">{` ${clean}` || "(empty)"}</div>
+                  <div class="note-row-preview">
+                    {" "}
+                    <Highlighted text={clean} ranges={note.matches} />
+                  </div>
                   <div class="note-row-top">
                     <span class="note-row-date">{fmtDate(note.createdAt)}</span>
                     <button
@@ -1,10 +1,22 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function AddClipTag(arg1:string,arg2:string):Promise<void>;
+
 export function ChooseNotesDir():Promise<string>;
 
 export function ClearClipboard():Promise<void>;
 
+export function CompleteLaunchQuery(arg1:string):Promise<Array<string>>;
+
+export function CopyClip(arg1:string):Promise<void>;
+
+export function CopySecretClip(arg1:string):Promise<void>;
+
+export function DeleteAppAlias(arg1:string):Promise<void>;
+
+export function DeleteCustomEntry(arg1:string):Promise<void>;
+
 export function DeleteNote(arg1:string):Promise<void>;
 
 export function ExecuteCommand(arg1:string):Promise<string>;
@@ -15,24 +27,66 @@ export function GetAppIcon(arg1:string):Promise<string>;
 
 export function GetClipData(arg1:string):Promise<string>;
 
+export function GetClipRecorderState():Promise<string>;
+
+export function GetClipThumbnail(arg1:string):Promise<string>;
+
+export function GetCustomEntries():Promise<string>;
+
 export function GetLastCommand():Promise<string>;
 
 export function GetLastOutput():Promise<string>;
 
+export function GetLaunchLog(arg1:string):Promise<string>;
+
 export function GetNotes():Promise<string>;
 
 export function GetNotesDir():Promise<string>;
 
+export function GetOpenWithApps(arg1:string):Promise<string>;
+
 export function Greet(arg1:string):Promise<string>;
 
 export function LaunchApp(arg1:string):Promise<void>;
 
+export function LaunchAppAction(arg1:string,arg2:string):Promise<void>;
+
+export function LaunchAppForQuery(arg1:string,arg2:string):Promise<void>;
+
+export function LaunchAppWithArgs(arg1:string,arg2:Array<string>,arg3:string):Promise<void>;
+
+export function OpenTarget(arg1:string):Promise<void>;
+
+export function OpenTargetWith(arg1:string,arg2:string):Promise<void>;
+
+export function PauseClipRecording():Promise<void>;
+
+export function PauseClipRecordingFor(arg1:number):Promise<void>;
+
+export function PinClip(arg1:string):Promise<void>;
+
+export function QueryClipData(arg1:string):Promise<string>;
+
 export function RegisterHotKey():Promise<void>;
 
+export function RemoveClipTag(arg1:string,arg2:string):Promise<void>;
+
+export function ResumeClipRecording():Promise<void>;
+
+export function SaveCustomEntry(arg1:string):Promise<string>;
+
 export function SaveNote(arg1:string):Promise<string>;
 
 export function SearchApps(arg1:string):Promise<string>;
 
-export function ToggleClipSecret(arg1:string):Promise<void>;
+export function SearchNotes(arg1:string):Promise<string>;
+
+export function SetAppAlias(arg1:string,arg2:string):Promise<void>;
+
+export function ToggleClipRecording():Promise<void>;
+
+export function ToggleClipSecret(arg1:string):Promise<string>;
+
+export function UnpinClip(arg1:string):Promise<void>;
 
 export function UpdateNote(arg1:string,arg2:string):Promise<string>;
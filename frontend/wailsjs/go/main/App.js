@@ -2,6 +2,10 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function AddClipTag(arg1, arg2) {
+  return window['go']['main']['App']['AddClipTag'](arg1, arg2);
+}
+
 export function ChooseNotesDir() {
   return window['go']['main']['App']['ChooseNotesDir']();
 }
@@ -10,6 +14,26 @@ export function ClearClipboard() {
   return window['go']['main']['App']['ClearClipboard']();
 }
 
+export function CompleteLaunchQuery(arg1) {
+  return window['go']['main']['App']['CompleteLaunchQuery'](arg1);
+}
+
+export function CopyClip(arg1) {
+  return window['go']['main']['App']['CopyClip'](arg1);
+}
+
+export function CopySecretClip(arg1) {
+  return window['go']['main']['App']['CopySecretClip'](arg1);
+}
+
+export function DeleteAppAlias(arg1) {
+  return window['go']['main']['App']['DeleteAppAlias'](arg1);
+}
+
+export function DeleteCustomEntry(arg1) {
+  return window['go']['main']['App']['DeleteCustomEntry'](arg1);
+}
+
 export function DeleteNote(arg1) {
   return window['go']['main']['App']['DeleteNote'](arg1);
 }
@@ -30,6 +54,18 @@ export function GetClipData(arg1) {
   return window['go']['main']['App']['GetClipData'](arg1);
 }
 
+export function GetClipRecorderState() {
+  return window['go']['main']['App']['GetClipRecorderState']();
+}
+
+export function GetClipThumbnail(arg1) {
+  return window['go']['main']['App']['GetClipThumbnail'](arg1);
+}
+
+export function GetCustomEntries() {
+  return window['go']['main']['App']['GetCustomEntries']();
+}
+
 export function GetLastCommand() {
   return window['go']['main']['App']['GetLastCommand']();
 }
@@ -38,6 +74,10 @@ export function GetLastOutput() {
   return window['go']['main']['App']['GetLastOutput']();
 }
 
+export function GetLaunchLog(arg1) {
+  return window['go']['main']['App']['GetLaunchLog'](arg1);
+}
+
 export function GetNotes() {
   return window['go']['main']['App']['GetNotes']();
 }
@@ -46,6 +86,10 @@ export function GetNotesDir() {
   return window['go']['main']['App']['GetNotesDir']();
 }
 
+export function GetOpenWithApps(arg1) {
+  return window['go']['main']['App']['GetOpenWithApps'](arg1);
+}
+
 export function Greet(arg1) {
   return window['go']['main']['App']['Greet'](arg1);
 }
@@ -54,10 +98,58 @@ export function LaunchApp(arg1) {
   return window['go']['main']['App']['LaunchApp'](arg1);
 }
 
+export function LaunchAppAction(arg1, arg2) {
+  return window['go']['main']['App']['LaunchAppAction'](arg1, arg2);
+}
+
+export function LaunchAppForQuery(arg1, arg2) {
+  return window['go']['main']['App']['LaunchAppForQuery'](arg1, arg2);
+}
+
+export function LaunchAppWithArgs(arg1, arg2, arg3) {
+  return window['go']['main']['App']['LaunchAppWithArgs'](arg1, arg2, arg3);
+}
+
+export function OpenTarget(arg1) {
+  return window['go']['main']['App']['OpenTarget'](arg1);
+}
+
+export function OpenTargetWith(arg1, arg2) {
+  return window['go']['main']['App']['OpenTargetWith'](arg1, arg2);
+}
+
+export function PauseClipRecording() {
+  return window['go']['main']['App']['PauseClipRecording']();
+}
+
+export function PauseClipRecordingFor(arg1) {
+  return window['go']['main']['App']['PauseClipRecordingFor'](arg1);
+}
+
+export function PinClip(arg1) {
+  return window['go']['main']['App']['PinClip'](arg1);
+}
+
+export function QueryClipData(arg1) {
+  return window['go']['main']['App']['QueryClipData'](arg1);
+}
+
 export function RegisterHotKey() {
   return window['go']['main']['App']['RegisterHotKey']();
 }
 
+export function RemoveClipTag(arg1, arg2) {
+  return window['go']['main']['App']['RemoveClipTag'](arg1, arg2);
+}
+
+export function ResumeClipRecording() {
+  return window['go']['main']['App']['ResumeClipRecording']();
+}
+
+export function SaveCustomEntry(arg1) {
+  return window['go']['main']['App']['SaveCustomEntry'](arg1);
+}
+
 export function SaveNote(arg1) {
   return window['go']['main']['App']['SaveNote'](arg1);
 }
@@ -66,10 +158,26 @@ export function SearchApps(arg1) {
   return window['go']['main']['App']['SearchApps'](arg1);
 }
 
+export function SearchNotes(arg1) {
+  return window['go']['main']['App']['SearchNotes'](arg1);
+}
+
+export function SetAppAlias(arg1, arg2) {
+  return window['go']['main']['App']['SetAppAlias'](arg1, arg2);
+}
+
+export function ToggleClipRecording() {
+  return window['go']['main']['App']['ToggleClipRecording']();
+}
+
 export function ToggleClipSecret(arg1) {
   return window['go']['main']['App']['ToggleClipSecret'](arg1);
 }
 
+export function UnpinClip(arg1) {
+  return window['go']['main']['App']['UnpinClip'](arg1);
+}
+
 export function UpdateNote(arg1, arg2) {
   return window['go']['main']['App']['UpdateNote'](arg1, arg2);
 }
@@ -0,0 +1,151 @@
+package appm
+
+import (
+	"os"
+	"path/filepath"
+	"rilaunch/pkg/config"
+	"sort"
+	"strings"
+)
+
+// maxPathCompletions bounds the number of completions returned for a path.
+const maxPathCompletions = 20
+
+// SplitLaunchQuery separates a query such as "code ~/src/project" into the
+// app part and the arguments to launch it with. The rest of the query only
+// counts as arguments when one of its tokens looks like a path, URL or
+// option, so multi-word names like "visual studio" stay a single query.
+func SplitLaunchQuery(query string) (string, []string) {
+	query = strings.TrimSpace(query)
+	head, rest, ok := strings.Cut(query, " ")
+	if !ok {
+		return query, nil
+	}
+	args := splitQueryArgs(strings.TrimSpace(rest))
+	for _, arg := range args {
+		if looksLikeArg(arg) {
+			return head, args
+		}
+	}
+	return query, nil
+}
+
+// splitQueryArgs tokenizes typed arguments with the Exec quoting rules,
+// falling back to whitespace splitting while a quote is still open.
+func splitQueryArgs(s string) []string {
+	if args, err := splitExec(s); err == nil {
+		return args
+	}
+	return strings.Fields(s)
+}
+
+func looksLikeArg(arg string) bool {
+	switch {
+	case strings.HasPrefix(arg, "~"), strings.HasPrefix(arg, "."), strings.HasPrefix(arg, "-"):
+		return true
+	case strings.Contains(arg, "://"), strings.ContainsAny(arg, `/\`):
+		return true
+	}
+	ext := filepath.Ext(arg)
+	return len(ext) > 1 && len(ext) < len(arg)
+}
+
+// resolveLaunchArg expands "~" and environment variables, and resolves
+// relative paths against the home directory, where the launcher's own
+// working directory would make no sense. Options and URLs are kept as-is.
+func resolveLaunchArg(arg string) string {
+	if strings.HasPrefix(arg, "-") || strings.Contains(arg, "://") {
+		return arg
+	}
+	arg = config.ExpandPath(arg)
+	if filepath.IsAbs(arg) {
+		return arg
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return arg
+	}
+	if candidate := filepath.Join(home, arg); fileExists(candidate) {
+		return candidate
+	}
+	return arg
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// CompletePath returns the directory entries that complete partial, in the
+// form it was typed in ("~/Doc" completes to "~/Documents/"). Directories end
+// with a separator; hidden entries are only listed for a partial name that
+// starts with a dot.
+func CompletePath(partial string) []string {
+	dirPart, namePart := "", partial
+	if i := strings.LastIndexAny(partial, `/\`); i >= 0 {
+		dirPart, namePart = partial[:i+1], partial[i+1:]
+	}
+
+	if dirPart == "" && strings.HasPrefix(partial, "~") {
+		return nil
+	}
+
+	dir := config.ExpandPath(dirPart)
+	if !filepath.IsAbs(dir) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil
+		}
+		dir = filepath.Join(home, dir)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	lowerName := strings.ToLower(namePart)
+	var completions []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") && !strings.HasPrefix(namePart, ".") {
+			continue
+		}
+		if !strings.HasPrefix(strings.ToLower(name), lowerName) {
+			continue
+		}
+		completion := dirPart + name
+		if entry.IsDir() {
+			completion += string(filepath.Separator)
+		}
+		completions = append(completions, completion)
+	}
+	sort.Strings(completions)
+	if len(completions) > maxPathCompletions {
+		completions = completions[:maxPathCompletions]
+	}
+	return completions
+}
+
+// CompleteLaunchQuery completes the last argument of a query such as
+// "gimp ~/Pic", returning whole queries ready to replace the typed one.
+func CompleteLaunchQuery(query string) []string {
+	i := strings.LastIndexAny(query, " \t")
+	if i < 0 {
+		return nil
+	}
+	prefix, last := query[:i+1], query[i+1:]
+	var queries []string
+	for _, completion := range CompletePath(last) {
+		queries = append(queries, prefix+quoteQueryArg(completion))
+	}
+	return queries
+}
+
+// quoteQueryArg quotes a completed path containing spaces.
+func quoteQueryArg(arg string) string {
+	if !strings.ContainsAny(arg, " \t\"") {
+		return arg
+	}
+	return quoteExecArg(arg)
+}
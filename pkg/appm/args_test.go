@@ -0,0 +1,105 @@
+package appm
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestSplitLaunchQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		head  string
+		args  []string
+	}{
+		{"gimp", "gimp", nil},
+		{"  gimp  ", "gimp", nil},
+		{"visual studio", "visual studio", nil},
+		{"visual studio code", "visual studio code", nil},
+		{"gimp ", "gimp", nil},
+		{"gimp ~/shot.png", "gimp", []string{"~/shot.png"}},
+		{"gimp shot.png", "gimp", []string{"shot.png"}},
+		{"code .", "code", []string{"."}},
+		{"firefox https://example.com", "firefox", []string{"https://example.com"}},
+		{"firefox --private-window", "firefox", []string{"--private-window"}},
+		{`gimp "~/My Pictures/shot.png"`, "gimp", []string{"~/My Pictures/shot.png"}},
+		{`gimp ~/My\ Pictures/a.png b.png`, "gimp", []string{"~/My Pictures/a.png", "b.png"}},
+		{`gimp "~/My Pictures`, "gimp", []string{`"~/My`, "Pictures"}},
+		{"gimp a b c.png", "gimp", []string{"a", "b", "c.png"}},
+	}
+	for _, tt := range tests {
+		head, args := SplitLaunchQuery(tt.query)
+		if head != tt.head || !reflect.DeepEqual(args, tt.args) {
+			t.Errorf("SplitLaunchQuery(%q) = %q, %q, want %q, %q", tt.query, head, args, tt.head, tt.args)
+		}
+	}
+}
+
+func TestCompletePath(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("completions use the Unix home directory")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	for _, dir := range []string{"Documents", "Downloads", "Music", ".config", "My Files"} {
+		if err := os.Mkdir(filepath.Join(home, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, file := range []string{"notes.txt", "Documents/report.pdf", "Documents/Readme.md", "My Files/a b.txt"} {
+		if err := os.WriteFile(filepath.Join(home, file), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	abs := filepath.Join(home, "Documents")
+
+	tests := []struct {
+		partial string
+		want    []string
+	}{
+		{"~/Do", []string{"~/Documents/", "~/Downloads/"}},
+		{"~/do", []string{"~/Documents/", "~/Downloads/"}},
+		{"~/", []string{"~/Documents/", "~/Downloads/", "~/Music/", "~/My Files/", "~/notes.txt"}},
+		{"~/.c", []string{"~/.config/"}},
+		{"~/Documents/re", []string{"~/Documents/Readme.md", "~/Documents/report.pdf"}},
+		{"~/My Files/a", []string{"~/My Files/a b.txt"}},
+		{"Mu", []string{"Music/"}},
+		{abs + "/rep", []string{abs + "/report.pdf"}},
+		{"~/Missing/", nil},
+		{"~/Documents/zz", nil},
+		{"~", nil},
+		{"~user", nil},
+	}
+	for _, tt := range tests {
+		if got := CompletePath(tt.partial); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("CompletePath(%q) = %q, want %q", tt.partial, got, tt.want)
+		}
+	}
+}
+
+func TestCompleteLaunchQuery(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("completions use the Unix home directory")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if err := os.Mkdir(filepath.Join(home, "My Files"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"gimp", nil},
+		{"gimp ~/My", []string{`gimp "~/My Files/"`}},
+		{"gimp ", []string{`gimp "My Files/"`}},
+	}
+	for _, tt := range tests {
+		if got := CompleteLaunchQuery(tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("CompleteLaunchQuery(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
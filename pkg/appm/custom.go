@@ -0,0 +1,355 @@
+package appm
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"rilaunch/pkg/config"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// customIDPrefix marks the IDs of apps defined in the custom entries file.
+const customIDPrefix = "custom:"
+
+// CustomEntry is a user-defined launcher item. It either runs Command, an
+// Exec-style command line, or opens Open, a URL or file, with the default
+// handler.
+type CustomEntry struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Keywords    []string `json:"keywords,omitempty"`
+	Icon        string   `json:"icon,omitempty"`
+	Command     string   `json:"command,omitempty"`
+	Open        string   `json:"open,omitempty"`
+	Terminal    bool     `json:"terminal,omitempty"`
+	WorkDir     string   `json:"workDir,omitempty"`
+}
+
+// CustomConfig is the content of the custom entries file.
+type CustomConfig struct {
+	Entries []CustomEntry `json:"entries"`
+	// Aliases maps an alias, such as "ff", to the ID or name of an app.
+	Aliases map[string]string `json:"aliases"`
+}
+
+// customMu serializes read-modify-write cycles of the custom entries file.
+var customMu sync.Mutex
+
+// CustomConfigPath returns the path of the custom entries file, which lives
+// next to settings.json.
+func CustomConfigPath() string {
+	dir, _ := config.GetDefaultConfigDir()
+	return filepath.Join(dir, "entries.json")
+}
+
+// LoadCustomConfig reads the custom entries file. A missing file is an empty
+// configuration.
+func LoadCustomConfig() (*CustomConfig, error) {
+	c := &CustomConfig{Aliases: make(map[string]string)}
+	data, err := os.ReadFile(CustomConfigPath())
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", filepath.Base(CustomConfigPath()), err)
+	}
+	if c.Aliases == nil {
+		c.Aliases = make(map[string]string)
+	}
+	return c, nil
+}
+
+// SaveCustomConfig writes the custom entries file, replacing it atomically so
+// the file watcher never sees it half-written.
+func SaveCustomConfig(c *CustomConfig) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := CustomConfigPath()
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (e *CustomEntry) validate() error {
+	if strings.TrimSpace(e.Name) == "" {
+		return fmt.Errorf("custom entry has no name")
+	}
+	if (e.Command == "") == (e.Open == "") {
+		return fmt.Errorf("custom entry %q needs either a command or something to open", e.Name)
+	}
+	if e.Command != "" {
+		if _, err := splitExec(e.Command); err != nil {
+			return fmt.Errorf("custom entry %q: %w", e.Name, err)
+		}
+	}
+	return nil
+}
+
+// UpsertCustomEntry adds entry, or replaces the entry with the same ID. An
+// entry without an ID gets one derived from its name.
+func UpsertCustomEntry(entry CustomEntry) (CustomEntry, error) {
+	if err := entry.validate(); err != nil {
+		return entry, err
+	}
+
+	customMu.Lock()
+	defer customMu.Unlock()
+
+	c, err := LoadCustomConfig()
+	if err != nil {
+		return entry, err
+	}
+
+	if entry.ID == "" {
+		entry.ID = uniqueCustomID(c, entry.Name)
+	}
+	replaced := false
+	for i := range c.Entries {
+		if c.Entries[i].ID == entry.ID {
+			c.Entries[i] = entry
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		c.Entries = append(c.Entries, entry)
+	}
+	return entry, SaveCustomConfig(c)
+}
+
+// DeleteCustomEntry removes the entry with the given ID, which may be the
+// entry's own ID, such as "team-wiki", or its app ID, "custom:team-wiki".
+func DeleteCustomEntry(id string) error {
+	customMu.Lock()
+	defer customMu.Unlock()
+
+	c, err := LoadCustomConfig()
+	if err != nil {
+		return err
+	}
+	for i := range c.Entries {
+		if c.Entries[i].ID == id || customIDPrefix+c.Entries[i].ID == id {
+			c.Entries = append(c.Entries[:i], c.Entries[i+1:]...)
+			return SaveCustomConfig(c)
+		}
+	}
+	return fmt.Errorf("custom entry not found: %s", id)
+}
+
+// SetAlias makes alias stand for target, an app ID or name.
+func SetAlias(alias, target string) error {
+	alias, target = strings.TrimSpace(alias), strings.TrimSpace(target)
+	if alias == "" || target == "" {
+		return fmt.Errorf("alias and target must not be empty")
+	}
+
+	customMu.Lock()
+	defer customMu.Unlock()
+
+	c, err := LoadCustomConfig()
+	if err != nil {
+		return err
+	}
+	c.Aliases[alias] = target
+	return SaveCustomConfig(c)
+}
+
+func DeleteAlias(alias string) error {
+	customMu.Lock()
+	defer customMu.Unlock()
+
+	c, err := LoadCustomConfig()
+	if err != nil {
+		return err
+	}
+	if _, ok := c.Aliases[alias]; !ok {
+		return fmt.Errorf("alias not found: %s", alias)
+	}
+	delete(c.Aliases, alias)
+	return SaveCustomConfig(c)
+}
+
+// uniqueCustomID derives an ID such as "team-wiki" from name, adding a
+// number if it is taken.
+func uniqueCustomID(c *CustomConfig, name string) string {
+	base := strings.Trim(strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + 'a' - 'A'
+		}
+		return '-'
+	}, name), "-")
+	if base == "" {
+		base = "entry"
+	}
+
+	taken := make(map[string]bool)
+	for _, e := range c.Entries {
+		taken[e.ID] = true
+	}
+	id := base
+	for n := 2; taken[id]; n++ {
+		id = base + "-" + strconv.Itoa(n)
+	}
+	return id
+}
+
+func customApp(e CustomEntry, source string) AppInfo {
+	app := AppInfo{
+		ID:          customIDPrefix + e.ID,
+		Name:        e.Name,
+		DisplayName: e.Name,
+		Description: e.Description,
+		Icon:        e.Icon,
+		Path:        e.Command,
+		Category:    "Custom",
+		Keywords:    e.Keywords,
+		WorkDir:     config.ExpandPath(e.WorkDir),
+		Terminal:    e.Terminal,
+		Source:      source,
+	}
+	if e.Open != "" {
+		app.Path = e.Open
+	}
+	if app.Description == "" {
+		app.Description = "Custom entry"
+	}
+	return app
+}
+
+// SetCustomConfig replaces the custom entries and aliases of the index.
+func (am *AppManager) SetCustomConfig(c *CustomConfig, source string) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	am.removeAppsLocked(func(app AppInfo) bool {
+		return strings.HasPrefix(app.ID, customIDPrefix)
+	})
+	am.aliases = c.Aliases
+	am.custom = make(map[string]CustomEntry, len(c.Entries))
+	for i := range am.apps {
+		am.apps[i].Aliases = am.aliasesForLocked(am.apps[i])
+	}
+	for i := range am.shadowed {
+		am.shadowed[i].Aliases = am.aliasesForLocked(am.shadowed[i])
+	}
+	for _, e := range c.Entries {
+		if err := e.validate(); err != nil {
+			fmt.Printf("Warning: skipping %v\n", err)
+			continue
+		}
+		app := customApp(e, source)
+		am.custom[app.ID] = e
+		am.addLocked(app)
+	}
+}
+
+// aliasesForLocked returns the aliases whose target is the app's ID or,
+// ignoring case, its name.
+func (am *AppManager) aliasesForLocked(app AppInfo) []string {
+	var aliases []string
+	for alias, target := range am.aliases {
+		if target == app.ID || strings.EqualFold(target, app.DisplayName) || strings.EqualFold(target, app.Name) {
+			aliases = append(aliases, alias)
+		}
+	}
+	sort.Strings(aliases)
+	return aliases
+}
+
+func (am *AppManager) customEntry(appID string) (CustomEntry, bool) {
+	am.mu.RLock()
+	defer am.mu.RUnlock()
+	e, ok := am.custom[appID]
+	return e, ok
+}
+
+// launchCustom starts a custom entry: commands run like an Exec line on Linux
+// and through the system shell elsewhere, while URLs and files are opened
+// with the default handler.
+func (am *AppManager) launchCustom(app *AppInfo, e CustomEntry) error {
+	if e.Open != "" {
+		return openWithDefault(config.ExpandPath(e.Open))
+	}
+
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "linux":
+		return am.launchAppByPath(app, e.Command, app.Icon)
+	case "windows":
+		cmd = exec.Command("cmd", "/c", e.Command)
+	default:
+		cmd = exec.Command("/bin/sh", "-c", e.Command)
+	}
+	cmd.Dir = app.WorkDir
+	return startTracked(app, cmd)
+}
+
+// quoteShellArg quotes an argument appended to a custom command, which runs
+// through cmd.exe on Windows and /bin/sh on other systems but Linux.
+func quoteShellArg(arg string) string {
+	if runtime.GOOS == "windows" {
+		return quoteCmdArg(arg)
+	}
+	return quoteExecArg(arg)
+}
+
+// quoteCmdArg quotes an argument for a cmd.exe command line. Quotes are
+// doubled, and backslashes are left alone except before a quote, where the
+// program parsing its command line would read them as escapes.
+func quoteCmdArg(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\"&|<>^()%!,;=") {
+		return arg
+	}
+	var b strings.Builder
+	b.WriteByte('"')
+	slashes := 0
+	for i := 0; i < len(arg); i++ {
+		switch arg[i] {
+		case '\\':
+			slashes++
+		case '"':
+			b.WriteString(strings.Repeat(`\`, slashes))
+			b.WriteByte('"')
+			slashes = 0
+		default:
+			slashes = 0
+		}
+		b.WriteByte(arg[i])
+	}
+	b.WriteString(strings.Repeat(`\`, slashes))
+	b.WriteByte('"')
+	return b.String()
+}
+
+// openWithDefault opens a URL or file with the desktop's default handler.
+func openWithDefault(target string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "linux":
+		cmd = exec.Command("xdg-open", target)
+	case "darwin":
+		cmd = exec.Command("open", target)
+	case "windows":
+		cmd = exec.Command("cmd", "/c", "start", "", target)
+	default:
+		return fmt.Errorf("unsupported operating system: %s", runtime.GOOS)
+	}
+	return startDetached(cmd)
+}
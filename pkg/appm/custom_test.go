@@ -0,0 +1,155 @@
+package appm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestQuoteCmdArg(t *testing.T) {
+	tests := []struct {
+		arg  string
+		want string
+	}{
+		{`notes.txt`, `notes.txt`},
+		{`C:\Users\me\notes.txt`, `C:\Users\me\notes.txt`},
+		{``, `""`},
+		{`C:\Program Files\app.exe`, `"C:\Program Files\app.exe"`},
+		{`C:\My Dir\`, `"C:\My Dir\\"`},
+		{`say "hi"`, `"say ""hi"""`},
+		{`a\"b`, `"a\\""b"`},
+		{`50%`, `"50%"`},
+		{`a&b`, `"a&b"`},
+	}
+	for _, tt := range tests {
+		if got := quoteCmdArg(tt.arg); got != tt.want {
+			t.Errorf("quoteCmdArg(%q) = %s, want %s", tt.arg, got, tt.want)
+		}
+	}
+}
+
+func TestCustomEntries(t *testing.T) {
+	t.Setenv("PAL_CONFIG_DIR", t.TempDir())
+
+	wiki, err := UpsertCustomEntry(CustomEntry{Name: "Team Wiki", Open: "https://wiki.example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wiki.ID != "team-wiki" {
+		t.Errorf("ID = %q, want team-wiki", wiki.ID)
+	}
+	second, err := UpsertCustomEntry(CustomEntry{Name: "Team Wiki!", Command: "firefox wiki"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.ID != "team-wiki-2" {
+		t.Errorf("second ID = %q, want team-wiki-2", second.ID)
+	}
+
+	wiki.Description = "Docs"
+	if _, err := UpsertCustomEntry(wiki); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, e := range []CustomEntry{
+		{Name: " ", Command: "true"},
+		{Name: "Both", Command: "true", Open: "/tmp"},
+		{Name: "Neither"},
+		{Name: "Unclosed", Command: `sh -c "echo`},
+	} {
+		if _, err := UpsertCustomEntry(e); err == nil {
+			t.Errorf("UpsertCustomEntry(%+v) succeeded, want an error", e)
+		}
+	}
+
+	c, err := LoadCustomConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Entries) != 2 || c.Entries[0].Description != "Docs" {
+		t.Fatalf("entries = %+v, want the updated wiki and team-wiki-2", c.Entries)
+	}
+
+	// Entries are deleted by their own ID or by their app ID.
+	if err := DeleteCustomEntry("team-wiki"); err != nil {
+		t.Errorf("delete by entry ID: %v", err)
+	}
+	if err := DeleteCustomEntry(customIDPrefix + "team-wiki-2"); err != nil {
+		t.Errorf("delete by app ID: %v", err)
+	}
+	if err := DeleteCustomEntry("team-wiki"); err == nil {
+		t.Error("deleting a missing entry succeeded")
+	}
+	if c, _ := LoadCustomConfig(); len(c.Entries) != 0 {
+		t.Errorf("entries left after deletes: %+v", c.Entries)
+	}
+}
+
+func TestAliases(t *testing.T) {
+	t.Setenv("PAL_CONFIG_DIR", t.TempDir())
+
+	if err := SetAlias("ff", "firefox"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetAlias("  ", "firefox"); err == nil {
+		t.Error("empty alias accepted")
+	}
+	if err := DeleteAlias("nope"); err == nil {
+		t.Error("deleting a missing alias succeeded")
+	}
+	c, err := LoadCustomConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]string{"ff": "firefox"}; !reflect.DeepEqual(c.Aliases, want) {
+		t.Errorf("aliases = %v, want %v", c.Aliases, want)
+	}
+	if err := DeleteAlias("ff"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSetCustomConfig(t *testing.T) {
+	am := NewAppManager()
+	am.AddApp(AppInfo{ID: "firefox", Name: "Firefox", DisplayName: "Firefox"})
+	am.AddApp(AppInfo{ID: "gimp", Name: "GIMP", DisplayName: "GIMP"})
+
+	am.SetCustomConfig(&CustomConfig{
+		Entries: []CustomEntry{
+			{ID: "wiki", Name: "Wiki", Open: "https://wiki.example.com"},
+			{ID: "broken", Name: "Broken"},
+		},
+		Aliases: map[string]string{"ff": "firefox", "web": "Firefox", "w": customIDPrefix + "wiki"},
+	}, "entries.json")
+
+	if n := am.Count(); n != 3 {
+		t.Errorf("%d apps listed, want 3", n)
+	}
+	if _, ok := am.GetApp(customIDPrefix + "broken"); ok {
+		t.Error("invalid entry listed")
+	}
+	ff, _ := am.GetApp("firefox")
+	if want := []string{"ff", "web"}; !reflect.DeepEqual(ff.Aliases, want) {
+		t.Errorf("firefox aliases = %v, want %v", ff.Aliases, want)
+	}
+	wiki, ok := am.GetApp(customIDPrefix + "wiki")
+	if !ok || !reflect.DeepEqual(wiki.Aliases, []string{"w"}) {
+		t.Errorf("wiki = %+v, want it listed with alias w", wiki)
+	}
+	if _, ok := am.customEntry(customIDPrefix + "wiki"); !ok {
+		t.Error("wiki has no custom entry")
+	}
+
+	// Replacing the config drops the old entries and aliases.
+	am.SetCustomConfig(&CustomConfig{
+		Entries: []CustomEntry{{ID: "mail", Name: "Mail", Command: "thunderbird"}},
+	}, "entries.json")
+	if _, ok := am.GetApp(customIDPrefix + "wiki"); ok {
+		t.Error("old entry still listed")
+	}
+	if _, ok := am.GetApp(customIDPrefix + "mail"); !ok {
+		t.Error("new entry not listed")
+	}
+	if ff, _ := am.GetApp("firefox"); len(ff.Aliases) != 0 {
+		t.Errorf("firefox aliases = %v after replacing the config, want none", ff.Aliases)
+	}
+}
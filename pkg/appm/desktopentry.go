@@ -0,0 +1,242 @@
+package appm
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// desktopEntryGroup is the group holding the main entry of a .desktop file.
+const desktopEntryGroup = "Desktop Entry"
+
+// DesktopFile is a parsed freedesktop.org Desktop Entry file, see
+// https://specifications.freedesktop.org/desktop-entry-spec/latest/
+type DesktopFile struct {
+	Path   string
+	groups map[string]map[string]string
+	order  []string
+}
+
+func ParseDesktopFile(path string) (*DesktopFile, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	df, err := parseDesktopEntry(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	df.Path = path
+	return df, nil
+}
+
+func parseDesktopEntry(r io.Reader) (*DesktopFile, error) {
+	df, err := parseKeyFile(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(df.order) == 0 || df.order[0] != desktopEntryGroup {
+		return nil, fmt.Errorf("first group must be [%s]", desktopEntryGroup)
+	}
+	return df, nil
+}
+
+// parseKeyFile parses the group/key=value format shared by desktop entries
+// and icon theme index files.
+func parseKeyFile(r io.Reader) (*DesktopFile, error) {
+	df := &DesktopFile{groups: make(map[string]map[string]string)}
+
+	var current map[string]string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: malformed group header %q", lineNo, line)
+			}
+			name := line[1 : len(line)-1]
+			if _, dup := df.groups[name]; dup {
+				// Duplicate groups are invalid; keep the first one.
+				current = nil
+				continue
+			}
+			current = make(map[string]string)
+			df.groups[name] = current
+			df.order = append(df.order, name)
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key=value, got %q", lineNo, line)
+		}
+		if current == nil {
+			// Entries before the first group, or inside a duplicate one.
+			continue
+		}
+		key = strings.TrimSpace(key)
+		if _, dup := current[key]; !dup {
+			current[key] = strings.TrimSpace(value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return df, nil
+}
+
+// Groups returns the group names in file order.
+func (df *DesktopFile) Groups() []string {
+	return df.order
+}
+
+func (df *DesktopFile) HasGroup(group string) bool {
+	_, ok := df.groups[group]
+	return ok
+}
+
+// Value returns the raw value of a key, before unescaping.
+func (df *DesktopFile) Value(group, key string) (string, bool) {
+	v, ok := df.groups[group][key]
+	return v, ok
+}
+
+func (df *DesktopFile) String(group, key string) string {
+	v, _ := df.Value(group, key)
+	return unescapeValue(v)
+}
+
+// LocaleString returns the value best matching the current locale, falling
+// back to the unlocalized key.
+func (df *DesktopFile) LocaleString(group, key string) string {
+	for _, locale := range currentLocales() {
+		if v, ok := df.Value(group, key+"["+locale+"]"); ok {
+			return unescapeValue(v)
+		}
+	}
+	return df.String(group, key)
+}
+
+func (df *DesktopFile) Strings(group, key string) []string {
+	v, _ := df.Value(group, key)
+	return splitList(v)
+}
+
+func (df *DesktopFile) LocaleStrings(group, key string) []string {
+	for _, locale := range currentLocales() {
+		if v, ok := df.Value(group, key+"["+locale+"]"); ok {
+			return splitList(v)
+		}
+	}
+	return df.Strings(group, key)
+}
+
+func (df *DesktopFile) Bool(group, key string) bool {
+	return df.String(group, key) == "true"
+}
+
+// unescapeValue expands the \s, \n, \t, \r and \\ escapes of string values.
+func unescapeValue(v string) string {
+	if !strings.Contains(v, `\`) {
+		return v
+	}
+	var b strings.Builder
+	for i := 0; i < len(v); i++ {
+		if v[i] != '\\' || i+1 == len(v) {
+			b.WriteByte(v[i])
+			continue
+		}
+		i++
+		switch v[i] {
+		case 's':
+			b.WriteByte(' ')
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		case '\\':
+			b.WriteByte('\\')
+		default:
+			b.WriteByte('\\')
+			b.WriteByte(v[i])
+		}
+	}
+	return b.String()
+}
+
+// splitList splits a ';'-separated list value, honouring "\;" escapes.
+func splitList(v string) []string {
+	var items []string
+	var b strings.Builder
+	for i := 0; i < len(v); i++ {
+		switch {
+		case v[i] == '\\' && i+1 < len(v) && v[i+1] == ';':
+			b.WriteByte(';')
+			i++
+		case v[i] == '\\' && i+1 < len(v):
+			b.WriteByte(v[i])
+			b.WriteByte(v[i+1])
+			i++
+		case v[i] == ';':
+			items = append(items, unescapeValue(b.String()))
+			b.Reset()
+		default:
+			b.WriteByte(v[i])
+		}
+	}
+	if b.Len() > 0 {
+		items = append(items, unescapeValue(b.String()))
+	}
+	return items
+}
+
+// currentLocales returns the locale keys to try for localized values, most
+// specific first, following the lang_COUNTRY@MODIFIER matching rules.
+func currentLocales() []string {
+	var locale string
+	for _, env := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if locale = os.Getenv(env); locale != "" {
+			break
+		}
+	}
+	if locale == "" || locale == "C" || locale == "POSIX" {
+		return nil
+	}
+
+	locale, modifier, _ := strings.Cut(locale, "@")
+	locale, _, _ = strings.Cut(locale, ".") // drop the encoding
+	lang, country, _ := strings.Cut(locale, "_")
+
+	var locales []string
+	if country != "" && modifier != "" {
+		locales = append(locales, lang+"_"+country+"@"+modifier)
+	}
+	if country != "" {
+		locales = append(locales, lang+"_"+country)
+	}
+	if modifier != "" {
+		locales = append(locales, lang+"@"+modifier)
+	}
+	return append(locales, lang)
+}
+
+// currentDesktops returns the desktop environment names from XDG_CURRENT_DESKTOP.
+func currentDesktops() []string {
+	var desktops []string
+	for _, d := range strings.Split(os.Getenv("XDG_CURRENT_DESKTOP"), ":") {
+		if d != "" {
+			desktops = append(desktops, d)
+		}
+	}
+	return desktops
+}
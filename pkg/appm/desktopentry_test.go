@@ -0,0 +1,176 @@
+package appm
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestUnescapeValue(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{`plain`, `plain`},
+		{`a\sb`, "a b"},
+		{`line\nbreak`, "line\nbreak"},
+		{`tab\there`, "tab\there"},
+		{`cr\r`, "cr\r"},
+		{`back\\slash`, `back\slash`},
+		{`\\s`, `\s`},
+		{`unknown\q`, `unknown\q`},
+		{`trailing\`, `trailing\`},
+	}
+	for _, tt := range tests {
+		if got := unescapeValue(tt.value); got != tt.want {
+			t.Errorf("unescapeValue(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	tests := []struct {
+		value string
+		want  []string
+	}{
+		{``, nil},
+		{`GNOME;KDE;`, []string{"GNOME", "KDE"}},
+		{`GNOME;KDE`, []string{"GNOME", "KDE"}},
+		{`a\;b;c;`, []string{"a;b", "c"}},
+		{`a\\;b;`, []string{`a\`, "b"}},
+		{`with\sspace;`, []string{"with space"}},
+		{`;x;`, []string{"", "x"}},
+	}
+	for _, tt := range tests {
+		if got := splitList(tt.value); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitList(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestCurrentLocales(t *testing.T) {
+	tests := []struct {
+		lcAll, lcMessages, lang string
+		want                    []string
+	}{
+		{"", "", "", nil},
+		{"", "", "C", nil},
+		{"", "", "POSIX", nil},
+		{"", "", "fr", []string{"fr"}},
+		{"", "", "de_DE.UTF-8", []string{"de_DE", "de"}},
+		{"", "", "sr@latin", []string{"sr@latin", "sr"}},
+		{"", "", "sr_RS.UTF-8@latin", []string{"sr_RS@latin", "sr_RS", "sr@latin", "sr"}},
+		{"", "pt_BR", "de_DE", []string{"pt_BR", "pt"}},
+		{"en_GB", "pt_BR", "de_DE", []string{"en_GB", "en"}},
+	}
+	for _, tt := range tests {
+		t.Setenv("LC_ALL", tt.lcAll)
+		t.Setenv("LC_MESSAGES", tt.lcMessages)
+		t.Setenv("LANG", tt.lang)
+		if got := currentLocales(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("LC_ALL=%q LC_MESSAGES=%q LANG=%q: currentLocales() = %q, want %q",
+				tt.lcAll, tt.lcMessages, tt.lang, got, tt.want)
+		}
+	}
+}
+
+func TestParseDesktopEntry(t *testing.T) {
+	const entry = `# comment
+[Desktop Entry]
+Name=Files
+Name[de]=Dateien
+Name[sr@latin]=Datoteke
+Comment=Browse\sthe\tfile system
+Keywords=folder;manager;
+Keywords[de]=Ordner;Verwaltung;
+Name=Duplicate keys keep the first value
+
+[Desktop Action new-window]
+Name=New Window
+`
+	df, err := parseDesktopEntry(strings.NewReader(entry))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := df.Groups(); !reflect.DeepEqual(got, []string{desktopEntryGroup, "Desktop Action new-window"}) {
+		t.Errorf("groups = %q", got)
+	}
+	if got := df.String(desktopEntryGroup, "Comment"); got != "Browse the\tfile system" {
+		t.Errorf("Comment = %q", got)
+	}
+
+	tests := []struct {
+		lang     string
+		name     string
+		keywords []string
+	}{
+		{"C", "Files", []string{"folder", "manager"}},
+		{"de_AT.UTF-8", "Dateien", []string{"Ordner", "Verwaltung"}},
+		{"sr_RS@latin", "Datoteke", []string{"folder", "manager"}},
+	}
+	for _, tt := range tests {
+		t.Setenv("LC_ALL", "")
+		t.Setenv("LC_MESSAGES", "")
+		t.Setenv("LANG", tt.lang)
+		if got := df.LocaleString(desktopEntryGroup, "Name"); got != tt.name {
+			t.Errorf("LANG=%s: Name = %q, want %q", tt.lang, got, tt.name)
+		}
+		if got := df.LocaleStrings(desktopEntryGroup, "Keywords"); !reflect.DeepEqual(got, tt.keywords) {
+			t.Errorf("LANG=%s: Keywords = %q, want %q", tt.lang, got, tt.keywords)
+		}
+	}
+
+	for _, bad := range []string{
+		"[Other]\nName=x\n[Desktop Entry]\n",
+		"[Desktop Entry\nName=x\n",
+		"[Desktop Entry]\nNo equals sign\n",
+	} {
+		if _, err := parseDesktopEntry(strings.NewReader(bad)); err == nil {
+			t.Errorf("parseDesktopEntry(%q) succeeded, want an error", bad)
+		}
+	}
+}
+
+func TestCheckDesktopEntryVisible(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("TryExec looks up executables by Unix file mode")
+	}
+	tryExec := filepath.Join(t.TempDir(), "tool")
+	if err := os.WriteFile(tryExec, []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("XDG_CURRENT_DESKTOP", "ubuntu:GNOME")
+
+	tests := []struct {
+		name    string
+		entry   string
+		visible bool
+	}{
+		{"plain", "", true},
+		{"link type", "Type=Link", false},
+		{"hidden", "Hidden=true", false},
+		{"no display", "NoDisplay=true", false},
+		{"hidden false", "Hidden=false", true},
+		{"only shown in this desktop", "OnlyShowIn=KDE;GNOME;", true},
+		{"only shown in this desktop, other case", "OnlyShowIn=gnome;", true},
+		{"only shown elsewhere", "OnlyShowIn=KDE;XFCE;", false},
+		{"not shown in this desktop", "NotShowIn=Unity;ubuntu;", false},
+		{"not shown elsewhere", "NotShowIn=KDE;", true},
+		{"try exec found", "TryExec=" + tryExec, true},
+		{"try exec missing", "TryExec=" + tryExec + "-missing", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			df, err := parseDesktopEntry(strings.NewReader("[Desktop Entry]\nName=App\nExec=app\n" + tt.entry + "\n"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := checkDesktopEntryVisible(df); (err == nil) != tt.visible {
+				t.Errorf("checkDesktopEntryVisible(%q) = %v, want visible %v", tt.entry, err, tt.visible)
+			}
+		})
+	}
+}
@@ -5,11 +5,18 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"rilaunch/pkg/config"
 	"runtime"
 	"strings"
 )
 
 func (am *AppManager) DiscoverApps() error {
+	// Read once here: on Linux and Windows the list depends on the settings.
+	dirs := AppDirs()
+	am.mu.Lock()
+	am.dirs = dirs
+	am.mu.Unlock()
+
 	switch runtime.GOOS {
 	case "linux":
 		return am.discoverLinuxApps()
@@ -22,15 +29,56 @@ func (am *AppManager) DiscoverApps() error {
 	}
 }
 
+// AppDirs returns the directories applications are discovered in.
+func AppDirs() []string {
+	switch runtime.GOOS {
+	case "linux":
+		return linuxAppDirs()
+	case "darwin":
+		return macOSAppDirs()
+	case "windows":
+		return windowsAppDirs()
+	}
+	return nil
+}
+
+func linuxAppDirs() []string {
+	return append(desktopAppDirs(), appImageDirs()...)
+}
+
+func macOSAppDirs() []string {
+	return []string{
+		"/Applications",
+		"/System/Applications",
+		filepath.Join(os.Getenv("HOME"), "Applications"),
+	}
+}
+
+// windowsAppDirs returns the Start Menu folders, plus the Program Files
+// folders when .exe scanning is enabled in the settings.
+func windowsAppDirs() []string {
+	dirs := []string{
+		filepath.Join(os.Getenv("APPDATA"), "Microsoft\\Windows\\Start Menu\\Programs"),
+		filepath.Join(os.Getenv("ProgramData"), "Microsoft\\Windows\\Start Menu\\Programs"),
+	}
+	if config.LoadSettings().ScanProgramFiles {
+		dirs = append(dirs, "C:\\Program Files", "C:\\Program Files (x86)")
+	}
+	return dirs
+}
+
+// discoverLinuxApps scans the applications directories in precedence order;
+// the first entry with a given desktop file ID wins, even a hidden one.
 func (am *AppManager) discoverLinuxApps() error {
-	desktopDirs := []string{
-		"/usr/share/applications",
-		"/usr/local/share/applications",
-		filepath.Join(os.Getenv("HOME"), ".local/share/applications"),
+	seen := make(map[string]bool)
+	for _, dir := range desktopAppDirs() {
+		if err := am.scanDesktopFiles(dir, seen); err != nil {
+			fmt.Printf("Warning: failed to scan %s: %v\n", dir, err)
+		}
 	}
 
-	for _, dir := range desktopDirs {
-		if err := am.scanDesktopFiles(dir); err != nil {
+	for _, dir := range appImageDirs() {
+		if err := am.scanAppImages(dir); err != nil {
 			fmt.Printf("Warning: failed to scan %s: %v\n", dir, err)
 		}
 	}
@@ -39,13 +87,7 @@ func (am *AppManager) discoverLinuxApps() error {
 }
 
 func (am *AppManager) discoverMacOSApps() error {
-	appDirs := []string{
-		"/Applications",
-		"/System/Applications",
-		filepath.Join(os.Getenv("HOME"), "Applications"),
-	}
-
-	for _, dir := range appDirs {
+	for _, dir := range macOSAppDirs() {
 		if err := am.scanMacOSApps(dir); err != nil {
 			fmt.Printf("Warning: failed to scan %s: %v\n", dir, err)
 		}
@@ -55,14 +97,7 @@ func (am *AppManager) discoverMacOSApps() error {
 }
 
 func (am *AppManager) discoverWindowsApps() error {
-	programDirs := []string{
-		"C:\\Program Files",
-		"C:\\Program Files (x86)",
-		filepath.Join(os.Getenv("APPDATA"), "Microsoft\\Windows\\Start Menu\\Programs"),
-		filepath.Join(os.Getenv("ProgramData"), "Microsoft\\Windows\\Start Menu\\Programs"),
-	}
-
-	for _, dir := range programDirs {
+	for _, dir := range windowsAppDirs() {
 		if err := am.scanWindowsApps(dir); err != nil {
 			fmt.Printf("Warning: failed to scan %s: %v\n", dir, err)
 		}
@@ -71,7 +106,7 @@ func (am *AppManager) discoverWindowsApps() error {
 	return nil
 }
 
-func (am *AppManager) scanDesktopFiles(dir string) error {
+func (am *AppManager) scanDesktopFiles(dir string, seen map[string]bool) error {
 	if _, err := os.Stat(dir); os.IsNotExist(err) {
 		return nil
 	}
@@ -85,6 +120,12 @@ func (am *AppManager) scanDesktopFiles(dir string) error {
 			return nil
 		}
 
+		id := desktopFileID(path)
+		if seen[id] {
+			return nil
+		}
+		seen[id] = true
+
 		app, err := am.parseDesktopFile(path)
 		if err != nil {
 			return nil
@@ -134,7 +175,7 @@ func (am *AppManager) scanWindowsApps(dir string) error {
 		}
 		if strings.HasSuffix(strings.ToLower(path), ".exe") || strings.HasSuffix(strings.ToLower(path), ".lnk") {
 			app := am.parseWindowsApp(path)
-			if app.Name != "" {
+			if app != nil && app.Name != "" {
 				am.AddApp(*app)
 			}
 		}
@@ -144,46 +185,109 @@ func (am *AppManager) scanWindowsApps(dir string) error {
 }
 
 func (am *AppManager) parseDesktopFile(path string) (*AppInfo, error) {
-	content, err := os.ReadFile(path)
+	df, err := ParseDesktopFile(path)
 	if err != nil {
 		return nil, err
 	}
+	if err := checkDesktopEntryVisible(df); err != nil {
+		return nil, err
+	}
+	return desktopApp(df, path), nil
+}
 
-	lines := strings.Split(string(content), "\n")
+// desktopApp builds the AppInfo of a parsed desktop entry.
+func desktopApp(df *DesktopFile, path string) *AppInfo {
+	g := desktopEntryGroup
 	app := &AppInfo{
-		ID:       filepath.Base(path),
-		Category: "Application",
-	}
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "Name=") {
-			app.Name = strings.TrimPrefix(line, "Name=")
-			app.DisplayName = app.Name
-		} else if strings.HasPrefix(line, "Comment=") {
-			app.Description = strings.TrimPrefix(line, "Comment=")
-		} else if strings.HasPrefix(line, "Exec=") {
-			execLine := strings.TrimPrefix(line, "Exec=")
-			// Remove desktop entry field codes
-			execLine = strings.ReplaceAll(execLine, "%f", "")
-			execLine = strings.ReplaceAll(execLine, "%F", "")
-			execLine = strings.ReplaceAll(execLine, "%u", "")
-			execLine = strings.ReplaceAll(execLine, "%U", "")
-			app.Path = strings.TrimSpace(execLine)
-		} else if strings.HasPrefix(line, "Icon=") {
-			app.Icon = strings.TrimPrefix(line, "Icon=")
-		} else if strings.HasPrefix(line, "Categories=") {
-			categories := strings.TrimPrefix(line, "Categories=")
-			if categories != "" {
-				app.Category = strings.Split(categories, ";")[0]
-			}
-		} else if strings.HasPrefix(line, "Keywords=") {
-			keywords := strings.TrimPrefix(line, "Keywords=")
-			app.Keywords = strings.Split(keywords, ";")
+		ID:          desktopFileID(path),
+		Name:        df.LocaleString(g, "Name"),
+		Description: df.LocaleString(g, "Comment"),
+		Icon:        df.LocaleString(g, "Icon"),
+		Path:        df.String(g, "Exec"),
+		Category:    "Application",
+		Keywords:    df.LocaleStrings(g, "Keywords"),
+		WorkDir:     df.String(g, "Path"),
+		Terminal:    df.Bool(g, "Terminal"),
+		Source:      path,
+		WMClass:     df.String(g, "StartupWMClass"),
+		MimeTypes:   df.Strings(g, "MimeType"),
+	}
+	app.DisplayName = app.Name
+	if app.Description == "" {
+		app.Description = df.LocaleString(g, "GenericName")
+	}
+	if categories := df.Strings(g, "Categories"); len(categories) > 0 {
+		app.Category = categories[0]
+	}
+	app.Actions = parseDesktopActions(df, app)
+
+	return app
+}
+
+// parseDesktopActions reads the [Desktop Action <id>] groups listed in the
+// entry's Actions key. Actions without a name or command are skipped.
+func parseDesktopActions(df *DesktopFile, app *AppInfo) []AppAction {
+	var actions []AppAction
+	for _, id := range df.Strings(desktopEntryGroup, "Actions") {
+		g := "Desktop Action " + id
+		if !df.HasGroup(g) {
+			continue
+		}
+		action := AppAction{
+			ID:   id,
+			Name: df.LocaleString(g, "Name"),
+			Icon: df.LocaleString(g, "Icon"),
+			Path: df.String(g, "Exec"),
+		}
+		if action.Icon == "" {
+			action.Icon = app.Icon
+		}
+		if action.Name == "" || action.Path == "" {
+			continue
+		}
+		actions = append(actions, action)
+	}
+	return actions
+}
+
+// checkDesktopEntryVisible reports why an entry should not be listed, if it
+// should not: it is not an application, is hidden, is restricted to other
+// desktop environments, or its TryExec program is not installed.
+func checkDesktopEntryVisible(df *DesktopFile) error {
+	g := desktopEntryGroup
+	// Type is required by the spec, but tolerate entries that omit it.
+	if t := df.String(g, "Type"); t != "Application" && t != "" {
+		return fmt.Errorf("unsupported entry type %q", t)
+	}
+	if df.Bool(g, "Hidden") || df.Bool(g, "NoDisplay") {
+		return fmt.Errorf("entry is hidden")
+	}
+
+	desktops := currentDesktops()
+	if onlyShowIn := df.Strings(g, "OnlyShowIn"); len(onlyShowIn) > 0 && !containsAny(onlyShowIn, desktops) {
+		return fmt.Errorf("entry is only shown in %v", onlyShowIn)
+	}
+	if notShowIn := df.Strings(g, "NotShowIn"); containsAny(notShowIn, desktops) {
+		return fmt.Errorf("entry is not shown in %v", notShowIn)
+	}
+
+	if tryExec := df.String(g, "TryExec"); tryExec != "" {
+		if _, err := exec.LookPath(tryExec); err != nil {
+			return fmt.Errorf("TryExec %s not found: %w", tryExec, err)
 		}
 	}
+	return nil
+}
 
-	return app, nil
+func containsAny(list, values []string) bool {
+	for _, v := range values {
+		for _, item := range list {
+			if strings.EqualFold(item, v) {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 func (am *AppManager) parseMacOSApp(path string) *AppInfo {
@@ -197,19 +301,66 @@ func (am *AppManager) parseMacOSApp(path string) *AppInfo {
 		Path:        path,
 		Category:    "Application",
 		Icon:        "🖥️",
+		Source:      path,
 	}
 
-	infoPlist := filepath.Join(path, "Contents", "Info.plist")
-	if _, err := os.Stat(infoPlist); err == nil {
-		app.Description = fmt.Sprintf("macOS application: %s", name)
+	info, err := ReadBundleInfo(path)
+	if err != nil {
+		return app
+	}
+
+	if info.Name != "" {
+		app.DisplayName = info.Name
+	}
+	if info.Category != "" {
+		app.Category = info.Category
+	}
+	app.BundleID = info.Identifier
+	app.Version = info.Version
+
+	app.Description = "macOS application"
+	if info.Version != "" {
+		app.Description = fmt.Sprintf("macOS application, version %s", info.Version)
+	}
+	if info.Identifier != "" {
+		app.Keywords = append(app.Keywords, info.Identifier)
 	}
 
 	return app
 }
 
+// windowsLaunchableExts are the shortcut target types listed as apps; links
+// to documents, web pages and help files are skipped.
+var windowsLaunchableExts = map[string]bool{
+	".exe": true, ".com": true, ".bat": true, ".cmd": true, ".msc": true, ".cpl": true,
+}
+
+// windowsNoiseWords mark executables and shortcuts that are not apps users
+// want to launch, such as uninstallers and crash reporters.
+var windowsNoiseWords = []string{"uninstall", "unins0", "crashreport", "crashpad", "updater", "helper", "setup"}
+
+func isWindowsNoise(name string) bool {
+	name = strings.ToLower(name)
+	for _, word := range windowsNoiseWords {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseWindowsApp describes a Start Menu shortcut or an executable. It
+// returns nil for uninstallers, helpers and shortcuts to non-programs.
 func (am *AppManager) parseWindowsApp(path string) *AppInfo {
 	name := filepath.Base(path)
 	name = strings.TrimSuffix(name, filepath.Ext(name))
+	if isWindowsNoise(name) {
+		return nil
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".lnk") {
+		return am.parseWindowsShortcut(path, name)
+	}
 
 	app := &AppInfo{
 		ID:          name,
@@ -219,42 +370,165 @@ func (am *AppManager) parseWindowsApp(path string) *AppInfo {
 		Category:    "Application",
 		Icon:        "🖥️",
 		Description: fmt.Sprintf("Windows application: %s", name),
+		Source:      path,
+	}
+
+	return app
+}
+
+// parseWindowsShortcut describes a .lnk file. The shortcut itself stays the
+// launch path so Windows applies its arguments and working directory.
+func (am *AppManager) parseWindowsShortcut(path, name string) *AppInfo {
+	link, err := ReadShellLink(path)
+	if err != nil {
+		return nil
+	}
+
+	app := &AppInfo{
+		ID:          name,
+		Name:        name,
+		DisplayName: name,
+		Path:        path,
+		Category:    "Application",
+		Icon:        "🖥️",
+		Description: link.Description,
+		WorkDir:     link.WorkingDir,
+		Source:      path,
+	}
+
+	if link.TargetPath != "" {
+		target := filepath.Base(strings.ReplaceAll(link.TargetPath, `\`, "/"))
+		if !windowsLaunchableExts[strings.ToLower(filepath.Ext(target))] || isWindowsNoise(target) {
+			return nil
+		}
+		app.Keywords = append(app.Keywords, strings.TrimSuffix(target, filepath.Ext(target)))
+	}
+	if app.Description == "" {
+		app.Description = fmt.Sprintf("Windows application: %s", name)
+	}
+	if link.IconLocation != "" {
+		app.Icon = fmt.Sprintf("%s,%d", link.IconLocation, link.IconIndex)
 	}
 
 	return app
 }
 
 func (am *AppManager) LaunchApp(appID string) error {
-	var app *AppInfo
-	for _, a := range am.apps {
-		if a.ID == appID {
-			app = &a
-			break
+	app, ok := am.GetApp(appID)
+	if !ok {
+		return fmt.Errorf("application not found: %s", appID)
+	}
+
+	if e, ok := am.customEntry(appID); ok {
+		return am.launchCustom(&app, e)
+	}
+	return am.launchAppByPath(&app, app.Path, app.Icon)
+}
+
+// LaunchAppWithArgs starts the app with files, URLs or options, which are
+// substituted into the field codes of desktop entries.
+func (am *AppManager) LaunchAppWithArgs(appID string, args []string) error {
+	app, ok := am.GetApp(appID)
+	if !ok {
+		return fmt.Errorf("application not found: %s", appID)
+	}
+	return am.launchWithArgs(app, args)
+}
+
+func (am *AppManager) launchWithArgs(app AppInfo, args []string) error {
+	resolved := make([]string, len(args))
+	for i, arg := range args {
+		resolved[i] = resolveLaunchArg(arg)
+	}
+
+	path := app.Path
+	if e, ok := am.customEntry(app.ID); ok {
+		if e.Command == "" {
+			return fmt.Errorf("%s does not take arguments", app.DisplayName)
+		}
+		if runtime.GOOS != "linux" {
+			quoted := make([]string, len(resolved))
+			for i, arg := range resolved {
+				quoted[i] = quoteShellArg(arg)
+			}
+			e.Command += " " + strings.Join(quoted, " ")
+			return am.launchCustom(&app, e)
+		}
+		path = e.Command
+	}
+
+	switch runtime.GOOS {
+	case "linux":
+		cmds, err := desktopCommands(&app, path, app.Icon, resolved)
+		if err != nil {
+			return err
+		}
+		for _, cmd := range cmds {
+			if err := startTracked(&app, cmd); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "darwin":
+		cmd := exec.Command("open", openArgs(path, resolved)...)
+		return startTracked(&app, cmd)
+	case "windows":
+		cmd := exec.Command("cmd", append([]string{"/c", "start", "", path}, resolved...)...)
+		return startTracked(&app, cmd)
+	default:
+		return fmt.Errorf("unsupported operating system: %s", runtime.GOOS)
+	}
+}
+
+// openArgs returns the arguments for macOS open to start app with args:
+// URLs and existing files are opened as documents, and everything else is
+// passed to the app after --args rather than read by open as its options.
+func openArgs(app string, args []string) []string {
+	cmdArgs := []string{"-a", app}
+	var appArgs []string
+	for _, arg := range args {
+		if urlScheme(arg) != "" || (!strings.HasPrefix(arg, "-") && fileExists(arg)) {
+			cmdArgs = append(cmdArgs, arg)
+		} else {
+			appArgs = append(appArgs, arg)
 		}
 	}
+	if len(appArgs) > 0 {
+		cmdArgs = append(append(cmdArgs, "--args"), appArgs...)
+	}
+	return cmdArgs
+}
 
-	if app == nil {
+func (am *AppManager) LaunchAppAction(appID, actionID string) error {
+	app, ok := am.GetApp(appID)
+	if !ok {
 		return fmt.Errorf("application not found: %s", appID)
 	}
 
-	return am.launchAppByPath(app.Path)
+	for _, action := range app.Actions {
+		if action.ID == actionID {
+			return am.launchAppByPath(&app, action.Path, action.Icon)
+		}
+	}
+	return fmt.Errorf("action %s not found for application %s", actionID, appID)
 }
 
-func (am *AppManager) launchAppByPath(path string) error {
+// launchAppByPath starts path on behalf of app. On Linux, path is a desktop
+// entry Exec line (or a plain command) and is started directly, without a shell.
+func (am *AppManager) launchAppByPath(app *AppInfo, path, icon string) error {
 	switch runtime.GOOS {
 	case "linux":
-		parts := strings.Fields(path)
-		if len(parts) == 0 {
-			return fmt.Errorf("invalid path: %s", path)
+		cmd, err := desktopCommand(app, path, icon, nil)
+		if err != nil {
+			return err
 		}
-		cmd := exec.Command(parts[0], parts[1:]...)
-		return cmd.Start()
+		return startTracked(app, cmd)
 	case "darwin":
 		cmd := exec.Command("open", path)
-		return cmd.Start()
+		return startTracked(app, cmd)
 	case "windows":
 		cmd := exec.Command("cmd", "/c", "start", "", path)
-		return cmd.Start()
+		return startTracked(app, cmd)
 	default:
 		return fmt.Errorf("unsupported operating system: %s", runtime.GOOS)
 	}
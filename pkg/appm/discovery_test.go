@@ -0,0 +1,34 @@
+package appm
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestOpenArgs(t *testing.T) {
+	doc := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(doc, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(filepath.Dir(doc), "missing.txt")
+	const app = "/Applications/TextEdit.app"
+
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{nil, []string{"-a", app}},
+		{[]string{doc}, []string{"-a", app, doc}},
+		{[]string{"https://example.com"}, []string{"-a", app, "https://example.com"}},
+		{[]string{"-n"}, []string{"-a", app, "--args", "-n"}},
+		{[]string{"--new-window", doc}, []string{"-a", app, doc, "--args", "--new-window"}},
+		{[]string{missing, "hello"}, []string{"-a", app, "--args", missing, "hello"}},
+	}
+	for _, tt := range tests {
+		if got := openArgs(app, tt.args); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("openArgs(%q) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package appm
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"rilaunch/pkg/config"
+	"strings"
+)
+
+// splitExec tokenizes an Exec value following the Desktop Entry quoting
+// rules: arguments are separated by spaces and may be enclosed in double
+// quotes, inside which `"`, "`", "$" and "\" are escaped with a backslash.
+func splitExec(execLine string) ([]string, error) {
+	var args []string
+	var b strings.Builder
+	inArg, inQuotes := false, false
+
+	for i := 0; i < len(execLine); i++ {
+		c := execLine[i]
+		switch {
+		case inQuotes && c == '\\' && i+1 < len(execLine) && strings.IndexByte("\"`$\\", execLine[i+1]) >= 0:
+			i++
+			b.WriteByte(execLine[i])
+		case c == '"':
+			inQuotes = !inQuotes
+			inArg = true
+		case !inQuotes && c == '\\' && i+1 < len(execLine):
+			// Not allowed by the spec, but common enough to accept.
+			i++
+			b.WriteByte(execLine[i])
+			inArg = true
+		case !inQuotes && (c == ' ' || c == '\t' || c == '\n'):
+			if inArg {
+				args = append(args, b.String())
+				b.Reset()
+				inArg = false
+			}
+		default:
+			b.WriteByte(c)
+			inArg = true
+		}
+	}
+	if inQuotes {
+		return nil, fmt.Errorf("unterminated quote in %q", execLine)
+	}
+	if inArg {
+		args = append(args, b.String())
+	}
+	return args, nil
+}
+
+// expandExecArgs replaces the field codes of tokenized Exec arguments. %F and
+// %U expand to all files, %f and %u to the first one, and are dropped when
+// there are none. %i, %c and %k expand to the icon, name and desktop file
+// location; the deprecated codes are removed.
+func expandExecArgs(args, files []string, icon, name, location string) []string {
+	var expanded []string
+	for _, arg := range args {
+		switch arg {
+		case "%F", "%U":
+			expanded = append(expanded, files...)
+			continue
+		case "%f", "%u":
+			if len(files) > 0 {
+				expanded = append(expanded, files[0])
+			}
+			continue
+		case "%i":
+			if icon != "" {
+				expanded = append(expanded, "--icon", icon)
+			}
+			continue
+		}
+
+		var b strings.Builder
+		for i := 0; i < len(arg); i++ {
+			if arg[i] != '%' || i+1 == len(arg) {
+				b.WriteByte(arg[i])
+				continue
+			}
+			i++
+			switch arg[i] {
+			case '%':
+				b.WriteByte('%')
+			case 'f', 'u':
+				if len(files) > 0 {
+					b.WriteString(files[0])
+				}
+			case 'c':
+				b.WriteString(name)
+			case 'k':
+				b.WriteString(location)
+			default:
+				// %F %U %i are only valid as standalone arguments, and
+				// %d %D %n %N %v %m are deprecated.
+			}
+		}
+		if b.Len() > 0 {
+			expanded = append(expanded, b.String())
+		}
+	}
+	return expanded
+}
+
+// knownTerminals maps terminal emulators to the arguments that make them run
+// a command, in order of preference.
+var knownTerminals = []struct {
+	name string
+	args []string
+}{
+	{"x-terminal-emulator", []string{"-e"}},
+	{"gnome-terminal", []string{"--"}},
+	{"konsole", []string{"-e"}},
+	{"xfce4-terminal", []string{"-x"}},
+	{"kitty", nil},
+	{"alacritty", []string{"-e"}},
+	{"foot", nil},
+	{"wezterm", []string{"start", "--"}},
+	{"tilix", []string{"-e"}},
+	{"xterm", []string{"-e"}},
+}
+
+// terminalArgs wraps a command so it runs inside a terminal emulator. The
+// emulator is taken from the terminalCommand setting, then $TERMINAL, then
+// the first installed known terminal.
+func terminalArgs(args []string) ([]string, error) {
+	if cmd := config.CurrentSettings().TerminalCommand; cmd != "" {
+		prefix, err := splitExec(cmd)
+		if err != nil {
+			return nil, fmt.Errorf("invalid terminal command setting: %w", err)
+		}
+		return append(prefix, args...), nil
+	}
+
+	if term := os.Getenv("TERMINAL"); term != "" {
+		if _, err := exec.LookPath(term); err == nil {
+			for _, t := range knownTerminals {
+				if t.name == filepath.Base(term) {
+					return append(append([]string{term}, t.args...), args...), nil
+				}
+			}
+			return append([]string{term, "-e"}, args...), nil
+		}
+	}
+
+	for _, t := range knownTerminals {
+		if _, err := exec.LookPath(t.name); err == nil {
+			return append(append([]string{t.name}, t.args...), args...), nil
+		}
+	}
+	return nil, fmt.Errorf("no terminal emulator found for %s", args[0])
+}
+
+// execFileCodes reports whether tokenized Exec arguments contain a file or
+// URL field code, and whether that code accepts several files (%F or %U).
+func execFileCodes(args []string) (hasCode, multiple bool) {
+	for _, arg := range args {
+		switch {
+		case arg == "%F" || arg == "%U":
+			return true, true
+		case strings.Contains(arg, "%f") || strings.Contains(arg, "%u"):
+			hasCode = true
+		}
+	}
+	return hasCode, false
+}
+
+// desktopCommands builds the commands that open files with an Exec line. An
+// entry taking a single %f or %u is started once per file, as the spec
+// requires; without any field code the files are appended.
+func desktopCommands(app *AppInfo, execLine, icon string, files []string) ([]*exec.Cmd, error) {
+	args, err := splitExec(execLine)
+	if err != nil {
+		return nil, err
+	}
+	hasCode, multiple := execFileCodes(args)
+	if !hasCode && len(files) > 0 {
+		execLine += " %F"
+	}
+	if !hasCode || multiple || len(files) <= 1 {
+		cmd, err := desktopCommand(app, execLine, icon, files)
+		if err != nil {
+			return nil, err
+		}
+		return []*exec.Cmd{cmd}, nil
+	}
+
+	var cmds []*exec.Cmd
+	for _, file := range files {
+		cmd, err := desktopCommand(app, execLine, icon, []string{file})
+		if err != nil {
+			return nil, err
+		}
+		cmds = append(cmds, cmd)
+	}
+	return cmds, nil
+}
+
+// desktopCommand builds the command for an Exec line of a desktop entry,
+// honouring the entry's Path= and Terminal= keys.
+func desktopCommand(app *AppInfo, execLine, icon string, files []string) (*exec.Cmd, error) {
+	args, err := splitExec(execLine)
+	if err != nil {
+		return nil, err
+	}
+	args = expandExecArgs(args, files, icon, app.Name, app.Source)
+	if len(args) == 0 {
+		return nil, fmt.Errorf("invalid path: %s", execLine)
+	}
+
+	if app.Terminal {
+		if args, err = terminalArgs(args); err != nil {
+			return nil, err
+		}
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Dir = app.WorkDir
+	return cmd, nil
+}
+
+// startDetached starts the command in its own session so it outlives the
+// launcher, and reaps it once it exits.
+func startDetached(cmd *exec.Cmd) error {
+	detachProcess(cmd)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait()
+	return nil
+}
@@ -0,0 +1,108 @@
+package appm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitExec(t *testing.T) {
+	tests := []struct {
+		exec string
+		want []string
+	}{
+		{``, nil},
+		{`gimp %U`, []string{"gimp", "%U"}},
+		{"  vim \t-p\n%F ", []string{"vim", "-p", "%F"}},
+		{`"/opt/My App/bin/app" --flag`, []string{"/opt/My App/bin/app", "--flag"}},
+		{`sh -c "echo \"\$HOME\" \` + "`" + `date\` + "`" + `"`, []string{"sh", "-c", "echo \"$HOME\" `date`"}},
+		{`app "C:\\dir"`, []string{"app", `C:\dir`}},
+		{`app "\n stays"`, []string{"app", `\n stays`}},
+		{`app ""`, []string{"app", ""}},
+		{`app --name="two words"`, []string{"app", "--name=two words"}},
+		{`app My\ File`, []string{"app", "My File"}},
+	}
+	for _, tt := range tests {
+		got, err := splitExec(tt.exec)
+		if err != nil {
+			t.Errorf("splitExec(%q): %v", tt.exec, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitExec(%q) = %q, want %q", tt.exec, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{`app "unterminated`, `app "escaped end\"`} {
+		if _, err := splitExec(bad); err == nil {
+			t.Errorf("splitExec(%q) succeeded, want an error", bad)
+		}
+	}
+}
+
+func TestExpandExecArgs(t *testing.T) {
+	const (
+		icon     = "gimp"
+		name     = "GIMP"
+		location = "/usr/share/applications/gimp.desktop"
+	)
+	one := []string{"/tmp/a.png"}
+	two := []string{"/tmp/a.png", "/tmp/b b.png"}
+
+	tests := []struct {
+		args  []string
+		files []string
+		want  []string
+	}{
+		{[]string{"gimp", "%U"}, nil, []string{"gimp"}},
+		{[]string{"gimp", "%U"}, two, []string{"gimp", "/tmp/a.png", "/tmp/b b.png"}},
+		{[]string{"gimp", "%F"}, two, []string{"gimp", "/tmp/a.png", "/tmp/b b.png"}},
+		{[]string{"gimp", "%f"}, two, []string{"gimp", "/tmp/a.png"}},
+		{[]string{"gimp", "%u"}, nil, []string{"gimp"}},
+		{[]string{"gimp", "--file=%f"}, one, []string{"gimp", "--file=/tmp/a.png"}},
+		{[]string{"gimp", "--file=%f"}, nil, []string{"gimp", "--file="}},
+		{[]string{"gimp", "%i"}, nil, []string{"gimp", "--icon", "gimp"}},
+		{[]string{"gimp", "--class=%c", "%k"}, nil, []string{"gimp", "--class=GIMP", location}},
+		{[]string{"printf", "100%%"}, nil, []string{"printf", "100%"}},
+		{[]string{"gimp", "%d", "%D", "%n", "%N", "%v", "%m"}, one, []string{"gimp"}},
+		{[]string{"gimp", "x%Fy"}, one, []string{"gimp", "xy"}},
+		{[]string{"gimp", "end%"}, nil, []string{"gimp", "end%"}},
+	}
+	for _, tt := range tests {
+		got := expandExecArgs(tt.args, tt.files, icon, name, location)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("expandExecArgs(%q, %q) = %q, want %q", tt.args, tt.files, got, tt.want)
+		}
+	}
+
+	if got := expandExecArgs([]string{"gimp", "%i"}, nil, "", name, location); !reflect.DeepEqual(got, []string{"gimp"}) {
+		t.Errorf("%%i without an icon = %q, want it dropped", got)
+	}
+}
+
+func TestDesktopCommands(t *testing.T) {
+	app := &AppInfo{Name: "Viewer", Source: "/usr/share/applications/viewer.desktop"}
+	files := []string{"/tmp/a.png", "/tmp/b.png"}
+
+	tests := []struct {
+		exec string
+		want [][]string
+	}{
+		{"viewer %f", [][]string{{"viewer", "/tmp/a.png"}, {"viewer", "/tmp/b.png"}}},
+		{"viewer %F", [][]string{{"viewer", "/tmp/a.png", "/tmp/b.png"}}},
+		{"viewer", [][]string{{"viewer", "/tmp/a.png", "/tmp/b.png"}}},
+	}
+	for _, tt := range tests {
+		cmds, err := desktopCommands(app, tt.exec, "", files)
+		if err != nil {
+			t.Errorf("desktopCommands(%q): %v", tt.exec, err)
+			continue
+		}
+		var got [][]string
+		for _, cmd := range cmds {
+			got = append(got, cmd.Args)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("desktopCommands(%q) = %q, want %q", tt.exec, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package appm
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"rilaunch/pkg/config"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// maxLaunchSamples is how many recent launch timestamps are kept per app.
+	maxLaunchSamples = 10
+	// frecencyHalfLife is the age at which a launch counts for half as much.
+	frecencyHalfLife = 7 * 24 * time.Hour
+)
+
+// LaunchRecord is the persisted launch history of a single application.
+type LaunchRecord struct {
+	Count    int            `json:"count"`
+	Launches []int64        `json:"launches"` // unix millis, oldest first
+	Queries  map[string]int `json:"queries"`  // search query -> times picked
+}
+
+// LaunchHistory stores launch records in the LaunchHistory bucket, keyed by app ID.
+type LaunchHistory struct {
+	DB *bolt.DB
+}
+
+func (h *LaunchHistory) Record(appID, query string, at time.Time) error {
+	return h.DB.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(config.LaunchBucket)
+		if bucket == nil {
+			return fmt.Errorf("launch history not found")
+		}
+
+		var record LaunchRecord
+		if data := bucket.Get([]byte(appID)); data != nil {
+			if err := json.Unmarshal(data, &record); err != nil {
+				return err
+			}
+		}
+
+		record.Count++
+		record.Launches = append(record.Launches, at.UnixMilli())
+		if len(record.Launches) > maxLaunchSamples {
+			record.Launches = record.Launches[len(record.Launches)-maxLaunchSamples:]
+		}
+
+		if query = normalizeQuery(query); query != "" {
+			if record.Queries == nil {
+				record.Queries = make(map[string]int)
+			}
+			record.Queries[query]++
+		}
+
+		data, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(appID), data)
+	})
+}
+
+func (h *LaunchHistory) ReadAll() (map[string]LaunchRecord, error) {
+	records := make(map[string]LaunchRecord)
+	err := h.DB.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(config.LaunchBucket)
+		if bucket == nil {
+			return fmt.Errorf("launch history not found")
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			var record LaunchRecord
+			if err := json.Unmarshal(v, &record); err != nil {
+				return nil // skip malformed records
+			}
+			records[string(k)] = record
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// LastUsed returns the time of the most recent launch, or the zero time.
+func (r LaunchRecord) LastUsed() time.Time {
+	if len(r.Launches) == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(r.Launches[len(r.Launches)-1])
+}
+
+// Frecency scores the record by launch frequency, with each sampled launch
+// decaying exponentially with age. The sample sum is scaled up to the total
+// launch count so long-lived favourites keep their weight.
+func (r LaunchRecord) Frecency(now time.Time) float64 {
+	if len(r.Launches) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, ts := range r.Launches {
+		age := now.Sub(time.UnixMilli(ts))
+		if age < 0 {
+			age = 0
+		}
+		sum += math.Exp2(-float64(age) / float64(frecencyHalfLife))
+	}
+	return sum * float64(r.Count) / float64(len(r.Launches))
+}
+
+// QueryScore counts how often the app was picked for a query that starts
+// with the given one, so "te" benefits from earlier picks for "term".
+func (r LaunchRecord) QueryScore(query string) int {
+	query = normalizeQuery(query)
+	if query == "" {
+		return 0
+	}
+	score := 0
+	for picked, count := range r.Queries {
+		if strings.HasPrefix(picked, query) {
+			score += count
+		}
+	}
+	return score
+}
+
+func normalizeQuery(query string) string {
+	return strings.ToLower(strings.TrimSpace(query))
+}
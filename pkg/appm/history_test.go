@@ -0,0 +1,168 @@
+package appm
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"rilaunch/pkg/config"
+	"slices"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func newTestHistory(t *testing.T) *LaunchHistory {
+	t.Helper()
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "palcb.db"), 0o600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucket(config.LaunchBucket)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &LaunchHistory{DB: db}
+}
+
+func TestFrecency(t *testing.T) {
+	now := time.Now()
+	at := func(ago time.Duration) int64 { return now.Add(-ago).UnixMilli() }
+
+	tests := []struct {
+		name   string
+		better LaunchRecord
+		worse  LaunchRecord
+	}{
+		{"launched over never",
+			LaunchRecord{Count: 1, Launches: []int64{at(60 * 24 * time.Hour)}},
+			LaunchRecord{}},
+		{"recent over old",
+			LaunchRecord{Count: 1, Launches: []int64{at(time.Hour)}},
+			LaunchRecord{Count: 1, Launches: []int64{at(14 * 24 * time.Hour)}}},
+		{"often over once",
+			LaunchRecord{Count: 3, Launches: []int64{at(3 * time.Hour), at(2 * time.Hour), at(time.Hour)}},
+			LaunchRecord{Count: 1, Launches: []int64{at(time.Hour)}}},
+		{"long-lived favourite over its samples",
+			LaunchRecord{Count: 100, Launches: []int64{at(time.Hour)}},
+			LaunchRecord{Count: 1, Launches: []int64{at(time.Hour)}}},
+		{"several recent over many old",
+			LaunchRecord{Count: 3, Launches: []int64{at(3 * time.Hour), at(2 * time.Hour), at(time.Hour)}},
+			LaunchRecord{Count: 4, Launches: []int64{at(60 * 24 * time.Hour), at(59 * 24 * time.Hour), at(58 * 24 * time.Hour), at(57 * 24 * time.Hour)}}},
+	}
+	for _, tt := range tests {
+		if b, w := tt.better.Frecency(now), tt.worse.Frecency(now); b <= w {
+			t.Errorf("%s: frecency %v <= %v", tt.name, b, w)
+		}
+	}
+
+	// A launch counts for half as much a half-life later.
+	week := LaunchRecord{Count: 1, Launches: []int64{at(frecencyHalfLife)}}
+	if got := week.Frecency(now); got < 0.499 || got > 0.501 {
+		t.Errorf("frecency one half-life later = %v, want 0.5", got)
+	}
+	future := LaunchRecord{Count: 1, Launches: []int64{now.Add(time.Hour).UnixMilli()}}
+	if got := future.Frecency(now); got != 1 {
+		t.Errorf("frecency of a future launch = %v, want 1", got)
+	}
+}
+
+func TestQueryScore(t *testing.T) {
+	r := LaunchRecord{Queries: map[string]int{"term": 3, "terminal": 2, "files": 1}}
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"", 0},
+		{"te", 5},
+		{" TERM ", 5},
+		{"termi", 2},
+		{"f", 1},
+		{"x", 0},
+	}
+	for _, tt := range tests {
+		if got := r.QueryScore(tt.query); got != tt.want {
+			t.Errorf("QueryScore(%q) = %d, want %d", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestLaunchHistoryRecord(t *testing.T) {
+	h := newTestHistory(t)
+	start := time.Now()
+	for i := range maxLaunchSamples + 2 {
+		if err := h.Record("gimp", " GIMP ", start.Add(time.Duration(i)*time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := h.Record("gimp", "", start.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := h.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := records["gimp"]
+	if r.Count != maxLaunchSamples+3 {
+		t.Errorf("Count = %d, want %d", r.Count, maxLaunchSamples+3)
+	}
+	if len(r.Launches) != maxLaunchSamples {
+		t.Errorf("%d launches kept, want %d", len(r.Launches), maxLaunchSamples)
+	}
+	if got := r.LastUsed(); !got.Equal(time.UnixMilli(start.Add(time.Hour).UnixMilli())) {
+		t.Errorf("LastUsed = %v, want the last launch", got)
+	}
+	if got, _ := json.Marshal(r.Queries); string(got) != `{"gimp":12}` {
+		t.Errorf("Queries = %s, want the normalized query only", got)
+	}
+}
+
+// TestSearchOrder checks that picks for a query, then frecency, then names
+// order apps that match the query equally well.
+func TestSearchOrder(t *testing.T) {
+	h := newTestHistory(t)
+	m := NewManager()
+	m.SetHistory(h)
+	m.initialized = true
+	for _, name := range []string{"Term A", "Term B", "Term C"} {
+		m.appManager.AddApp(AppInfo{ID: name, Name: name, DisplayName: name})
+	}
+
+	order := func(query string) []string {
+		t.Helper()
+		raw, err := m.SearchApps(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var results []AppMatch
+		if err := json.Unmarshal([]byte(raw), &results); err != nil {
+			t.Fatal(err)
+		}
+		var ids []string
+		for _, r := range results {
+			ids = append(ids, r.ID)
+		}
+		return ids
+	}
+	check := func(step, query string, want ...string) {
+		t.Helper()
+		if got := order(query); !slices.Equal(got, want) {
+			t.Fatalf("%s: SearchApps(%q) = %v, want %v", step, query, got, want)
+		}
+	}
+
+	check("no history", "term", "Term A", "Term B", "Term C")
+
+	now := time.Now()
+	h.Record("Term C", "", now.Add(-time.Hour))
+	h.Record("Term B", "", now.Add(-30*24*time.Hour))
+	check("frecency", "term", "Term C", "Term B", "Term A")
+
+	h.Record("Term A", "te", now.Add(-60*24*time.Hour))
+	check("picked for the query", "term", "Term C", "Term B", "Term A")
+	check("picked for a longer query", "t", "Term A", "Term C", "Term B")
+}
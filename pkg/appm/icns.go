@@ -0,0 +1,175 @@
+package appm
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+)
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// icnsPNGTypes maps the icns element types that hold PNG (or JPEG 2000)
+// data to their pixel size.
+var icnsPNGTypes = map[string]int{
+	"icp4": 16, "icp5": 32, "icp6": 64,
+	"ic07": 128, "ic08": 256, "ic09": 512, "ic10": 1024,
+	"ic11": 32, "ic12": 64, "ic13": 256, "ic14": 512,
+}
+
+// icnsRLETypes maps the legacy RLE-compressed RGB element types to their
+// pixel size and alpha mask type.
+var icnsRLETypes = map[string]struct {
+	size int
+	mask string
+}{
+	"is32": {16, "s8mk"},
+	"il32": {32, "l8mk"},
+	"ih32": {48, "h8mk"},
+	"it32": {128, "t8mk"},
+}
+
+// DecodeICNS returns a PNG encoding of the image in an Apple icon container
+// closest to, and preferably not smaller than, size pixels.
+func DecodeICNS(data []byte, size int) ([]byte, error) {
+	if len(data) < 8 || string(data[:4]) != "icns" {
+		return nil, fmt.Errorf("icns: bad magic")
+	}
+
+	elements := make(map[string][]byte)
+	for off := 8; off+8 <= len(data); {
+		kind := string(data[off : off+4])
+		length := int(binary.BigEndian.Uint32(data[off+4 : off+8]))
+		if length < 8 || off+length > len(data) {
+			break
+		}
+		elements[kind] = data[off+8 : off+length]
+		off += length
+	}
+
+	type candidate struct {
+		size int
+		png  []byte
+		kind string
+	}
+	var candidates []candidate
+	for kind, body := range elements {
+		if s, ok := icnsPNGTypes[kind]; ok && bytes.HasPrefix(body, pngSignature) {
+			candidates = append(candidates, candidate{size: s, png: body, kind: kind})
+		}
+		if t, ok := icnsRLETypes[kind]; ok {
+			candidates = append(candidates, candidate{size: t.size, kind: kind})
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("icns: no supported images")
+	}
+
+	best := candidates[0]
+	for _, c := range candidates[1:] {
+		if betterIconSize(c.size, best.size, size) || c.size == best.size && c.png != nil && best.png == nil {
+			best = c
+		}
+	}
+
+	if best.png != nil {
+		return best.png, nil
+	}
+	t := icnsRLETypes[best.kind]
+	img, err := decodeICNSRLE(elements[best.kind], elements[t.mask], t.size, best.kind == "it32")
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// betterIconSize reports whether size a suits the wanted size better than b:
+// the smallest size at least as large as wanted, else the largest one.
+func betterIconSize(a, b, wanted int) bool {
+	switch {
+	case a >= wanted && b >= wanted:
+		return a < b
+	case a >= wanted:
+		return true
+	case b >= wanted:
+		return false
+	default:
+		return a > b
+	}
+}
+
+// decodeICNSRLE decodes the PackBits-style compressed RGB channels of the
+// legacy icns types, applying the 8-bit alpha mask when present. it32 data
+// starts with four zero bytes.
+func decodeICNSRLE(data, mask []byte, size int, it32 bool) (image.Image, error) {
+	if it32 && len(data) >= 4 {
+		data = data[4:]
+	}
+	pixels := size * size
+	channels := make([]byte, 0, pixels*3)
+
+	// Small icons may be stored uncompressed.
+	if len(data) == pixels*3 {
+		channels = append(channels, data...)
+	} else {
+		for i := 0; i < len(data) && len(channels) < pixels*3; {
+			n := int(data[i])
+			i++
+			if n < 0x80 {
+				end := i + n + 1
+				if end > len(data) {
+					return nil, fmt.Errorf("icns: truncated RLE data")
+				}
+				channels = append(channels, data[i:end]...)
+				i = end
+			} else {
+				if i >= len(data) {
+					return nil, fmt.Errorf("icns: truncated RLE data")
+				}
+				for j := 0; j < n-0x80+3; j++ {
+					channels = append(channels, data[i])
+				}
+				i++
+			}
+		}
+	}
+	if len(channels) < pixels*3 {
+		return nil, fmt.Errorf("icns: short RLE data")
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, size, size))
+	for p := 0; p < pixels; p++ {
+		alpha := uint8(0xff)
+		if len(mask) >= pixels {
+			alpha = mask[p]
+		}
+		img.SetNRGBA(p%size, p/size, color.NRGBA{
+			R: channels[p],
+			G: channels[pixels+p],
+			B: channels[2*pixels+p],
+			A: alpha,
+		})
+	}
+	return img, nil
+}
+
+// encodeICNSFile decodes an .icns file into a PNG data URI.
+func encodeICNSFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	pngData, err := DecodeICNS(data, 64)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", path, err)
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(pngData), nil
+}
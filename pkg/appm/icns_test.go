@@ -0,0 +1,64 @@
+package appm
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDecodeICNS(t *testing.T) {
+	tests := []struct {
+		file  string
+		size  int
+		wantW int
+		want  color.NRGBA
+	}{
+		// PNG elements are returned as they are, the closest size not
+		// smaller than wanted first.
+		{"png.icns", 16, 16, color.NRGBA{R: 0xff, A: 0xff}},
+		{"png.icns", 64, 128, color.NRGBA{B: 0xff, A: 0xff}},
+		{"png.icns", 512, 128, color.NRGBA{B: 0xff, A: 0xff}},
+		// is32 with runs in the R and G channels, literals in B, and an
+		// s8mk alpha mask.
+		{"rle.icns", 32, 16, color.NRGBA{R: 0xff, G: 0x80, B: 0x05, A: 0x40}},
+	}
+	for _, tt := range tests {
+		data, err := os.ReadFile(filepath.Join("testdata", tt.file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := DecodeICNS(data, tt.size)
+		if err != nil {
+			t.Errorf("%s at %d: %v", tt.file, tt.size, err)
+			continue
+		}
+		img, err := png.Decode(bytes.NewReader(out))
+		if err != nil {
+			t.Errorf("%s at %d: %v", tt.file, tt.size, err)
+			continue
+		}
+		if w := img.Bounds().Dx(); w != tt.wantW {
+			t.Errorf("%s at %d: got %dpx, want %dpx", tt.file, tt.size, w, tt.wantW)
+		}
+		if got := color.NRGBAModel.Convert(img.At(5, 0)); got != tt.want {
+			t.Errorf("%s at %d: pixel (5, 0) is %v, want %v", tt.file, tt.size, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeICNSCorrupt(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "rle.icns"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DecodeICNS([]byte("nope"), 32); err == nil {
+		t.Error("expected an error for data without icns magic")
+	}
+	// No prefix of a valid file may crash the decoder.
+	for n := range data {
+		DecodeICNS(data[:n], 32)
+	}
+}
@@ -0,0 +1,139 @@
+package appm
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"rilaunch/pkg/config"
+	"rilaunch/pkg/util"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// iconCacheMaxAge is how long a cached icon is kept without being used.
+	iconCacheMaxAge = 30 * 24 * time.Hour
+	// iconCacheMaxBytes bounds the size of the icon cache; the least
+	// recently used icons are removed beyond it.
+	iconCacheMaxBytes = 32 << 20
+)
+
+// pruneIconCacheOnce prunes the cache the first time it is used in a run.
+var pruneIconCacheOnce sync.Once
+
+// cachedIcon returns the data URI for an icon source file, reading it from
+// the on-disk cache when the source is unchanged. The cache key combines the
+// path and modification time, so updated icons are re-encoded.
+func cachedIcon(path string, encode func(path string) (string, error)) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+
+	dir, err := config.GetDefaultConfigDir()
+	if err != nil {
+		return "", err
+	}
+	cacheDir := filepath.Join(dir, "iconcache")
+	pruneIconCacheOnce.Do(func() {
+		go pruneIconCache(cacheDir, iconCacheMaxAge, iconCacheMaxBytes)
+	})
+	key := util.CalculateHash(fmt.Sprintf("%s\x00%d", path, info.ModTime().UnixNano()))
+	cacheFile := filepath.Join(cacheDir, key)
+
+	if data, err := os.ReadFile(cacheFile); err == nil {
+		// The modification time of a cached icon is when it was last
+		// used, updated at most daily.
+		if cached, err := os.Stat(cacheFile); err == nil && time.Since(cached.ModTime()) > 24*time.Hour {
+			now := time.Now()
+			os.Chtimes(cacheFile, now, now)
+		}
+		return string(data), nil
+	}
+
+	uri, err := encode(path)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(cacheDir, 0o700); err == nil {
+		os.WriteFile(cacheFile, []byte(uri), 0o600)
+	}
+	return uri, nil
+}
+
+// pruneIconCache removes the cached icons unused for longer than maxAge,
+// such as those of icons that were updated or uninstalled, then the least
+// recently used ones until the cache fits in maxBytes.
+func pruneIconCache(cacheDir string, maxAge time.Duration, maxBytes int64) {
+	entries, err := os.ReadDir(cacheDir)
+	if err != nil {
+		return
+	}
+
+	type cached struct {
+		path string
+		size int64
+		used time.Time
+	}
+	var kept []cached
+	var total int64
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		path := filepath.Join(cacheDir, e.Name())
+		if time.Since(info.ModTime()) > maxAge {
+			os.Remove(path)
+			continue
+		}
+		kept = append(kept, cached{path, info.Size(), info.ModTime()})
+		total += info.Size()
+	}
+
+	sort.Slice(kept, func(i, j int) bool { return kept[i].used.Before(kept[j].used) })
+	for _, c := range kept {
+		if total <= maxBytes {
+			break
+		}
+		if os.Remove(c.path) == nil {
+			total -= c.size
+		}
+	}
+}
+
+// encodeIconFile encodes a PNG or SVG file as a data URI.
+func encodeIconFile(path string) (string, error) {
+	var mime string
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".png":
+		mime = "image/png"
+	case ".svg":
+		mime = "image/svg+xml"
+	default:
+		return "", fmt.Errorf("unsupported icon format: %s", path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return "data:" + mime + ";base64," + base64.StdEncoding.EncodeToString(data), nil
+}
+
+// LinuxIconDataURI resolves a desktop entry Icon= value to a data URI.
+func LinuxIconDataURI(icon string, size int) string {
+	path := LookupIcon(icon, size)
+	if path == "" {
+		return ""
+	}
+	uri, err := cachedIcon(path, encodeIconFile)
+	if err != nil {
+		return ""
+	}
+	return uri
+}
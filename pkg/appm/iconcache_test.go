@@ -0,0 +1,99 @@
+package appm
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestPruneIconCache(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	icons := []struct {
+		name string
+		size int
+		used time.Duration
+	}{
+		{"stale", 10, 40 * 24 * time.Hour},
+		{"old", 40, 3 * time.Hour},
+		{"older", 40, 4 * time.Hour},
+		{"recent", 40, time.Hour},
+		{"new", 40, 0},
+	}
+	for _, icon := range icons {
+		path := filepath.Join(dir, icon.name)
+		if err := os.WriteFile(path, make([]byte, icon.size), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		used := now.Add(-icon.used)
+		if err := os.Chtimes(path, used, used); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pruneIconCache(dir, 30*24*time.Hour, 100)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var left []string
+	for _, e := range entries {
+		left = append(left, e.Name())
+	}
+	slices.Sort(left)
+	if want := []string{"new", "recent"}; !slices.Equal(left, want) {
+		t.Errorf("left %v, want %v", left, want)
+	}
+}
+
+func TestLookupIconExtension(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("PAL_CONFIG_DIR", filepath.Join(home, "config"))
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	t.Setenv("XDG_DATA_HOME", filepath.Join(home, ".local", "share"))
+	t.Setenv("XDG_DATA_DIRS", filepath.Join(home, "share"))
+	iconThemesMu.Lock()
+	clear(iconThemes)
+	iconThemesMu.Unlock()
+	t.Cleanup(func() {
+		iconThemesMu.Lock()
+		clear(iconThemes)
+		iconThemesMu.Unlock()
+	})
+
+	theme := filepath.Join(home, "share", "icons", "hicolor")
+	apps := filepath.Join(theme, "48x48", "apps")
+	if err := os.MkdirAll(apps, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	index := "[Icon Theme]\nName=Hicolor\nDirectories=48x48/apps\n\n[48x48/apps]\nSize=48\nType=Fixed\n"
+	if err := os.WriteFile(filepath.Join(theme, "index.theme"), []byte(index), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"gimp.png", "org.gnome.Files.svg"} {
+		if err := os.WriteFile(filepath.Join(apps, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		icon string
+		want string
+	}{
+		{"gimp", filepath.Join(apps, "gimp.png")},
+		{"gimp.png", filepath.Join(apps, "gimp.png")},
+		{"gimp.PNG", filepath.Join(apps, "gimp.png")},
+		{"gimp.svg", filepath.Join(apps, "gimp.png")},
+		{"org.gnome.Files", filepath.Join(apps, "org.gnome.Files.svg")},
+		{"missing.png", ""},
+	}
+	for _, tt := range tests {
+		if got := LookupIcon(tt.icon, 48); got != tt.want {
+			t.Errorf("LookupIcon(%q) = %q, want %q", tt.icon, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,303 @@
+package appm
+
+import (
+	"bufio"
+	"math"
+	"os"
+	"path/filepath"
+	"rilaunch/pkg/config"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// iconExtensions are the icon formats the webview can display, in order of
+// preference.
+var iconExtensions = []string{".png", ".svg"}
+
+// iconThemeDir is a subdirectory of an icon theme, see
+// https://specifications.freedesktop.org/icon-theme-spec/latest/
+type iconThemeDir struct {
+	path      string
+	size      int
+	scale     int
+	minSize   int
+	maxSize   int
+	threshold int
+	kind      string
+}
+
+type iconTheme struct {
+	name     string
+	dirs     []iconThemeDir
+	inherits []string
+}
+
+var (
+	iconThemes   = make(map[string]*iconTheme)
+	iconThemesMu sync.Mutex
+)
+
+// iconBaseDirs returns the directories icon themes are looked up in.
+func iconBaseDirs() []string {
+	dirs := []string{filepath.Join(os.Getenv("HOME"), ".icons")}
+	for _, dir := range xdgDataDirs() {
+		dirs = append(dirs, filepath.Join(dir, "icons"))
+	}
+	return dirs
+}
+
+// currentIconTheme returns the icon theme from the settings, falling back to
+// the GTK and KDE configuration and finally to hicolor.
+func currentIconTheme() string {
+	if theme := config.CurrentSettings().IconTheme; theme != "" {
+		return theme
+	}
+
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		configHome = filepath.Join(os.Getenv("HOME"), ".config")
+	}
+	for _, source := range []struct{ file, key string }{
+		{filepath.Join(configHome, "gtk-4.0", "settings.ini"), "gtk-icon-theme-name"},
+		{filepath.Join(configHome, "gtk-3.0", "settings.ini"), "gtk-icon-theme-name"},
+		{filepath.Join(configHome, "kdeglobals"), "Theme"},
+	} {
+		if theme := readIniValue(source.file, source.key); theme != "" {
+			return theme
+		}
+	}
+	return "hicolor"
+}
+
+// readIniValue returns the first value of key in a loosely formatted ini file.
+func readIniValue(path, key string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		k, v, ok := strings.Cut(scanner.Text(), "=")
+		if ok && strings.TrimSpace(k) == key {
+			return strings.Trim(strings.TrimSpace(v), `"`)
+		}
+	}
+	return ""
+}
+
+// loadIconTheme parses and caches the index.theme of the named theme.
+func loadIconTheme(name string) *iconTheme {
+	iconThemesMu.Lock()
+	defer iconThemesMu.Unlock()
+
+	if theme, ok := iconThemes[name]; ok {
+		return theme
+	}
+
+	var theme *iconTheme
+	for _, base := range iconBaseDirs() {
+		f, err := os.Open(filepath.Join(base, name, "index.theme"))
+		if err != nil {
+			continue
+		}
+		df, err := parseKeyFile(f)
+		f.Close()
+		if err != nil || !df.HasGroup("Icon Theme") {
+			continue
+		}
+		theme = parseIconTheme(name, df)
+		break
+	}
+	iconThemes[name] = theme
+	return theme
+}
+
+func parseIconTheme(name string, df *DesktopFile) *iconTheme {
+	theme := &iconTheme{
+		name:     name,
+		inherits: splitCommaList(df.String("Icon Theme", "Inherits")),
+	}
+	for _, dir := range splitCommaList(df.String("Icon Theme", "Directories")) {
+		if !df.HasGroup(dir) {
+			continue
+		}
+		size := keyFileInt(df, dir, "Size", 0)
+		d := iconThemeDir{
+			path:      dir,
+			size:      size,
+			scale:     keyFileInt(df, dir, "Scale", 1),
+			minSize:   keyFileInt(df, dir, "MinSize", size),
+			maxSize:   keyFileInt(df, dir, "MaxSize", size),
+			threshold: keyFileInt(df, dir, "Threshold", 2),
+			kind:      df.String(dir, "Type"),
+		}
+		if d.kind == "" {
+			d.kind = "Threshold"
+		}
+		theme.dirs = append(theme.dirs, d)
+	}
+	return theme
+}
+
+// splitCommaList splits the comma-separated lists used by index.theme.
+func splitCommaList(v string) []string {
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func keyFileInt(df *DesktopFile, group, key string, fallback int) int {
+	if v, err := strconv.Atoi(df.String(group, key)); err == nil {
+		return v
+	}
+	return fallback
+}
+
+func (d iconThemeDir) matchesSize(size, scale int) bool {
+	if d.scale != scale {
+		return false
+	}
+	switch d.kind {
+	case "Fixed":
+		return d.size == size
+	case "Scalable":
+		return d.minSize <= size && size <= d.maxSize
+	default:
+		return d.size-d.threshold <= size && size <= d.size+d.threshold
+	}
+}
+
+func (d iconThemeDir) sizeDistance(size, scale int) int {
+	switch d.kind {
+	case "Scalable":
+		if size*scale < d.minSize*d.scale {
+			return d.minSize*d.scale - size*scale
+		}
+		if size*scale > d.maxSize*d.scale {
+			return size*scale - d.maxSize*d.scale
+		}
+		return 0
+	case "Threshold":
+		if size*scale < (d.size-d.threshold)*d.scale {
+			return d.minSize*d.scale - size*scale
+		}
+		if size*scale > (d.size+d.threshold)*d.scale {
+			return size*scale - d.maxSize*d.scale
+		}
+		return 0
+	default:
+		return abs(d.size*d.scale - size*scale)
+	}
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// LookupIcon resolves an Icon= value to an image file, following the theme
+// inheritance chain, then hicolor, then the pixmaps directory. Absolute
+// paths are returned as-is when they exist. Icon names should not have an
+// extension, but an image one such as "foo.png" is ignored.
+func LookupIcon(icon string, size int) string {
+	if icon == "" {
+		return ""
+	}
+	if filepath.IsAbs(icon) {
+		if _, err := os.Stat(icon); err == nil {
+			return icon
+		}
+		return ""
+	}
+	switch strings.ToLower(filepath.Ext(icon)) {
+	case ".png", ".svg", ".xpm":
+		icon = strings.TrimSuffix(icon, filepath.Ext(icon))
+	}
+
+	visited := make(map[string]bool)
+	if path := lookupIconInTheme(icon, size, 1, currentIconTheme(), visited); path != "" {
+		return path
+	}
+	if path := lookupIconInTheme(icon, size, 1, "hicolor", visited); path != "" {
+		return path
+	}
+
+	for _, dir := range []string{"/usr/share/pixmaps", "/usr/local/share/pixmaps"} {
+		for _, ext := range iconExtensions {
+			path := filepath.Join(dir, icon+ext)
+			if _, err := os.Stat(path); err == nil {
+				return path
+			}
+		}
+	}
+	return ""
+}
+
+func lookupIconInTheme(icon string, size, scale int, name string, visited map[string]bool) string {
+	if visited[name] {
+		return ""
+	}
+	visited[name] = true
+
+	theme := loadIconTheme(name)
+	if theme == nil {
+		return ""
+	}
+	if path := theme.lookup(icon, size, scale); path != "" {
+		return path
+	}
+	for _, parent := range theme.inherits {
+		if path := lookupIconInTheme(icon, size, scale, parent, visited); path != "" {
+			return path
+		}
+	}
+	return ""
+}
+
+func (t *iconTheme) lookup(icon string, size, scale int) string {
+	bases := iconBaseDirs()
+
+	for _, d := range t.dirs {
+		if !d.matchesSize(size, scale) {
+			continue
+		}
+		for _, base := range bases {
+			for _, ext := range iconExtensions {
+				path := filepath.Join(base, t.name, d.path, icon+ext)
+				if _, err := os.Stat(path); err == nil {
+					return path
+				}
+			}
+		}
+	}
+
+	best, bestDistance := "", math.MaxInt
+	for _, d := range t.dirs {
+		distance := d.sizeDistance(size, scale)
+		if distance >= bestDistance {
+			continue
+		}
+		for _, base := range bases {
+			for _, ext := range iconExtensions {
+				path := filepath.Join(base, t.name, d.path, icon+ext)
+				if _, err := os.Stat(path); err == nil {
+					best, bestDistance = path, distance
+					break
+				}
+			}
+			if best != "" && bestDistance == distance {
+				break
+			}
+		}
+	}
+	return best
+}
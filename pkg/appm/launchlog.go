@@ -0,0 +1,224 @@
+package appm
+
+import (
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+const (
+	// earlyExitWindow is how long after starting an exit counts as a failed
+	// launch rather than the app being closed.
+	earlyExitWindow = 3 * time.Second
+	// maxStderrCapture bounds the stderr kept per launch.
+	maxStderrCapture = 8 << 10
+	// maxLaunchReports bounds the launch log.
+	maxLaunchReports = 100
+)
+
+// LaunchReport describes one launch: the command, its exit status once it
+// has exited, and what it wrote to stderr in its first seconds.
+type LaunchReport struct {
+	AppID     string    `json:"appId"`
+	Name      string    `json:"name"`
+	Command   []string  `json:"command"`
+	PID       int       `json:"pid,omitempty"`
+	StartedAt time.Time `json:"startedAt"`
+	ExitedAt  time.Time `json:"exitedAt,omitempty"`
+	Running   bool      `json:"running"`
+	// ExitCode is -1 while running or when killed by a signal.
+	ExitCode int    `json:"exitCode"`
+	Failed   bool   `json:"failed"`
+	Error    string `json:"error,omitempty"`
+	Stderr   string `json:"stderr,omitempty"`
+}
+
+type LaunchFailureCallback func(report LaunchReport)
+
+var launchFailureCallback LaunchFailureCallback
+
+// SetLaunchFailureCallback sets the function called when a launch fails to
+// start or its process exits with an error within earlyExitWindow.
+func SetLaunchFailureCallback(callback LaunchFailureCallback) {
+	launchFailureCallback = callback
+}
+
+// launchLog keeps the most recent launch reports, oldest first.
+type launchLog struct {
+	mu      sync.Mutex
+	reports []*LaunchReport
+}
+
+// launches survives index refreshes, like launched.
+var launches = &launchLog{}
+
+func (l *launchLog) add(r *LaunchReport) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.reports = append(l.reports, r)
+	if len(l.reports) > maxLaunchReports {
+		l.reports = append([]*LaunchReport(nil), l.reports[len(l.reports)-maxLaunchReports:]...)
+	}
+}
+
+// update changes a report under the log's lock and returns a copy of it.
+func (l *launchLog) update(r *LaunchReport, change func(r *LaunchReport)) LaunchReport {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	change(r)
+	return *r
+}
+
+// LaunchReports returns the launch log of the app, or of all apps if appID
+// is empty, newest first.
+func LaunchReports(appID string) []LaunchReport {
+	launches.mu.Lock()
+	defer launches.mu.Unlock()
+	reports := []LaunchReport{}
+	for i := len(launches.reports) - 1; i >= 0; i-- {
+		if r := launches.reports[i]; appID == "" || r.AppID == appID {
+			reports = append(reports, *r)
+		}
+	}
+	return reports
+}
+
+// stderrTruncateInterval is how often the stderr file of a running app is
+// emptied after earlyExitWindow, so chatty apps do not fill the disk.
+const stderrTruncateInterval = time.Minute
+
+// newStderrFile returns a temporary file for the stderr of a launch, opened
+// once for the child to append to and once for reading it back. A file
+// rather than a pipe, so the child never gets EPIPE when the launcher exits
+// before it.
+func newStderrFile() (child, reader *os.File, err error) {
+	reader, err = os.CreateTemp("", "rilaunch-stderr-*")
+	if err != nil {
+		return nil, nil, err
+	}
+	child, err = os.OpenFile(reader.Name(), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		reader.Close()
+		os.Remove(reader.Name())
+		return nil, nil, err
+	}
+	return child, reader, nil
+}
+
+// readTail returns the last maxStderrCapture bytes of f.
+func readTail(f *os.File) string {
+	if f == nil {
+		return ""
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return ""
+	}
+	off := max(info.Size()-maxStderrCapture, 0)
+	buf := make([]byte, info.Size()-off)
+	n, _ := f.ReadAt(buf, off)
+	return string(buf[:n])
+}
+
+// startTracked starts cmd detached, like startDetached, and tracks it as a
+// running instance of the app until it exits. The launch is recorded in the
+// launch log, and failures are reported to the launch failure callback.
+func startTracked(app *AppInfo, cmd *exec.Cmd) error {
+	started := time.Now()
+	report := &LaunchReport{
+		AppID:     app.ID,
+		Name:      app.DisplayName,
+		Command:   cmd.Args,
+		StartedAt: started,
+		ExitCode:  -1,
+	}
+
+	childStderr, stderr, fileErr := newStderrFile()
+	if fileErr == nil {
+		cmd.Stderr = childStderr
+	}
+
+	detachProcess(cmd)
+	err := cmd.Start()
+	if fileErr == nil {
+		childStderr.Close()
+		// Both ends stay open. On Windows, where open files cannot be
+		// removed, the file is removed again once the child has exited.
+		os.Remove(stderr.Name())
+	}
+	if err != nil {
+		if fileErr == nil {
+			stderr.Close()
+		}
+		report.Failed, report.Error = true, err.Error()
+		launches.add(report)
+		notifyLaunchFailure(*report)
+		return err
+	}
+
+	pid := cmd.Process.Pid
+	report.PID, report.Running = pid, true
+	launches.add(report)
+	launched.add(app.ID, pid)
+
+	exited := make(chan struct{})
+	go func() {
+		waitErr := cmd.Wait()
+		launched.remove(app.ID, pid)
+		now := time.Now()
+		failed := waitErr != nil && now.Sub(started) < earlyExitWindow
+
+		final := launches.update(report, func(r *LaunchReport) {
+			r.Running, r.ExitedAt = false, now
+			r.ExitCode = cmd.ProcessState.ExitCode()
+			r.Failed = failed
+			if waitErr != nil {
+				r.Error = waitErr.Error()
+			}
+			if failed {
+				r.Stderr = readTail(stderr)
+			}
+		})
+		close(exited)
+		if failed {
+			notifyLaunchFailure(final)
+		}
+	}()
+
+	if fileErr == nil {
+		go func() {
+			defer func() {
+				stderr.Close()
+				os.Remove(stderr.Name())
+			}()
+			select {
+			case <-exited:
+				return
+			case <-time.After(earlyExitWindow):
+			}
+			// Keep the stderr of long-running apps visible while they run.
+			launches.update(report, func(r *LaunchReport) {
+				r.Stderr = readTail(stderr)
+			})
+
+			ticker := time.NewTicker(stderrTruncateInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-exited:
+					return
+				case <-ticker.C:
+					stderr.Truncate(0)
+				}
+			}
+		}()
+	}
+	return nil
+}
+
+func notifyLaunchFailure(report LaunchReport) {
+	if launchFailureCallback != nil {
+		launchFailureCallback(report)
+	}
+}
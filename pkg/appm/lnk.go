@@ -0,0 +1,267 @@
+package appm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf16"
+)
+
+// ShellLink is the resolved content of a Windows .lnk shortcut, see
+// [MS-SHLLINK]: Shell Link (.LNK) Binary File Format.
+type ShellLink struct {
+	TargetPath   string
+	Arguments    string
+	WorkingDir   string
+	IconLocation string
+	IconIndex    int32
+	Description  string
+	RelativePath string
+}
+
+const (
+	lnkHeaderSize = 0x4c
+
+	lnkHasTargetIDList = 1 << 0
+	lnkHasLinkInfo     = 1 << 1
+	lnkHasName         = 1 << 2
+	lnkHasRelativePath = 1 << 3
+	lnkHasWorkingDir   = 1 << 4
+	lnkHasArguments    = 1 << 5
+	lnkHasIconLocation = 1 << 6
+	lnkIsUnicode       = 1 << 7
+
+	lnkVolumeIDAndLocalBasePath  = 1 << 0
+	lnkCommonNetworkRelativeLink = 1 << 1
+
+	lnkEnvironmentBlock     = 0xa0000001
+	lnkIconEnvironmentBlock = 0xa0000007
+)
+
+// lnkCLSID is the class identifier every shell link header carries.
+var lnkCLSID = []byte{0x01, 0x14, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0xc0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x46}
+
+func ReadShellLink(path string) (*ShellLink, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	link, err := ParseShellLink(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return link, nil
+}
+
+func ParseShellLink(data []byte) (*ShellLink, error) {
+	if len(data) < lnkHeaderSize || binary.LittleEndian.Uint32(data) != lnkHeaderSize || !bytes.Equal(data[4:20], lnkCLSID) {
+		return nil, fmt.Errorf("lnk: not a shell link")
+	}
+	flags := binary.LittleEndian.Uint32(data[20:24])
+	link := &ShellLink{IconIndex: int32(binary.LittleEndian.Uint32(data[56:60]))}
+	off := lnkHeaderSize
+
+	if flags&lnkHasTargetIDList != 0 {
+		if off+2 > len(data) {
+			return nil, fmt.Errorf("lnk: truncated ID list")
+		}
+		off += 2 + int(binary.LittleEndian.Uint16(data[off:]))
+		if off > len(data) {
+			return nil, fmt.Errorf("lnk: truncated ID list")
+		}
+	}
+
+	if flags&lnkHasLinkInfo != 0 {
+		if off+4 > len(data) {
+			return nil, fmt.Errorf("lnk: truncated link info")
+		}
+		size := int(binary.LittleEndian.Uint32(data[off:]))
+		if size < 0x1c || off+size > len(data) {
+			return nil, fmt.Errorf("lnk: invalid link info size")
+		}
+		link.TargetPath = parseLinkInfo(data[off : off+size])
+		off += size
+	}
+
+	for _, s := range []struct {
+		flag uint32
+		dst  *string
+	}{
+		{lnkHasName, &link.Description},
+		{lnkHasRelativePath, &link.RelativePath},
+		{lnkHasWorkingDir, &link.WorkingDir},
+		{lnkHasArguments, &link.Arguments},
+		{lnkHasIconLocation, &link.IconLocation},
+	} {
+		if flags&s.flag == 0 {
+			continue
+		}
+		if off > len(data) {
+			return nil, fmt.Errorf("lnk: truncated string data")
+		}
+		value, n, err := readLinkString(data[off:], flags&lnkIsUnicode != 0)
+		if err != nil {
+			return nil, err
+		}
+		*s.dst = value
+		off += n
+	}
+
+	// Extra data blocks hold environment-variable forms of the target and
+	// icon, used by installers that write %ProgramFiles%-relative links.
+	for off+8 <= len(data) {
+		size := int(binary.LittleEndian.Uint32(data[off:]))
+		if size < 8 || off+size > len(data) {
+			break
+		}
+		block := data[off : off+size]
+		switch binary.LittleEndian.Uint32(block[4:]) {
+		case lnkEnvironmentBlock:
+			if link.TargetPath == "" {
+				link.TargetPath = expandWindowsEnv(envBlockString(block))
+			}
+		case lnkIconEnvironmentBlock:
+			if icon := envBlockString(block); icon != "" {
+				link.IconLocation = icon
+			}
+		}
+		off += size
+	}
+
+	if link.TargetPath == "" && link.RelativePath != "" {
+		link.TargetPath = link.RelativePath
+	}
+	link.IconLocation = expandWindowsEnv(link.IconLocation)
+	link.WorkingDir = expandWindowsEnv(link.WorkingDir)
+	return link, nil
+}
+
+// parseLinkInfo returns the target path stored in a LinkInfo structure,
+// either a local base path or a network share, plus the common suffix.
+func parseLinkInfo(info []byte) string {
+	headerSize := binary.LittleEndian.Uint32(info[4:])
+	flags := binary.LittleEndian.Uint32(info[8:])
+	localBaseOff := binary.LittleEndian.Uint32(info[16:])
+	networkOff := binary.LittleEndian.Uint32(info[20:])
+	suffixOff := binary.LittleEndian.Uint32(info[24:])
+
+	var base, suffix string
+	if headerSize >= 0x24 && len(info) >= 0x24 {
+		// Unicode offsets are present and preferred.
+		if off := binary.LittleEndian.Uint32(info[28:]); flags&lnkVolumeIDAndLocalBasePath != 0 && off != 0 {
+			base = cStringUTF16(info, off)
+		}
+		if off := binary.LittleEndian.Uint32(info[32:]); off != 0 {
+			suffix = cStringUTF16(info, off)
+		}
+	}
+	if base == "" && flags&lnkVolumeIDAndLocalBasePath != 0 {
+		base = cString(info, localBaseOff)
+	}
+	if base == "" && flags&lnkCommonNetworkRelativeLink != 0 && int(networkOff)+12 <= len(info) {
+		netNameOff := binary.LittleEndian.Uint32(info[networkOff+8:])
+		base = cString(info, networkOff+netNameOff)
+	}
+	if suffix == "" && suffixOff != 0 {
+		suffix = cString(info, suffixOff)
+	}
+
+	if base == "" {
+		return ""
+	}
+	if suffix != "" && !strings.HasSuffix(base, `\`) {
+		base += `\`
+	}
+	return base + suffix
+}
+
+func readLinkString(data []byte, unicode bool) (string, int, error) {
+	if len(data) < 2 {
+		return "", 0, fmt.Errorf("lnk: truncated string data")
+	}
+	count := int(binary.LittleEndian.Uint16(data))
+	if !unicode {
+		if 2+count > len(data) {
+			return "", 0, fmt.Errorf("lnk: truncated string data")
+		}
+		return string(data[2 : 2+count]), 2 + count, nil
+	}
+	if 2+count*2 > len(data) {
+		return "", 0, fmt.Errorf("lnk: truncated string data")
+	}
+	return decodeUTF16LE(data[2 : 2+count*2]), 2 + count*2, nil
+}
+
+// envBlockString reads the target of an environment data block, preferring
+// the 520-byte Unicode field over the 260-byte ANSI one.
+func envBlockString(block []byte) string {
+	if len(block) >= 8+260+520 {
+		if s := cStringUTF16(block[8+260:8+260+520], 0); s != "" {
+			return s
+		}
+	}
+	if len(block) >= 8+260 {
+		return cString(block[8:8+260], 0)
+	}
+	return ""
+}
+
+func cString(data []byte, off uint32) string {
+	if int(off) >= len(data) {
+		return ""
+	}
+	rest := data[off:]
+	if i := bytes.IndexByte(rest, 0); i >= 0 {
+		rest = rest[:i]
+	}
+	return string(rest)
+}
+
+func cStringUTF16(data []byte, off uint32) string {
+	if int(off) >= len(data) {
+		return ""
+	}
+	rest := data[off:]
+	for i := 0; i+1 < len(rest); i += 2 {
+		if rest[i] == 0 && rest[i+1] == 0 {
+			return decodeUTF16LE(rest[:i])
+		}
+	}
+	return decodeUTF16LE(rest[:len(rest)&^1])
+}
+
+func decodeUTF16LE(b []byte) string {
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(b[i*2:])
+	}
+	return string(utf16.Decode(units))
+}
+
+// expandWindowsEnv expands %VAR% references using the current environment,
+// leaving unknown variables in place.
+func expandWindowsEnv(s string) string {
+	var b strings.Builder
+	for {
+		start := strings.IndexByte(s, '%')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(s[start+1:], '%')
+		if end < 0 {
+			break
+		}
+		end += start + 1
+		b.WriteString(s[:start])
+		if v, ok := os.LookupEnv(s[start+1 : end]); ok {
+			b.WriteString(v)
+		} else {
+			b.WriteString(s[start : end+1])
+		}
+		s = s[end+1:]
+	}
+	b.WriteString(s)
+	return b.String()
+}
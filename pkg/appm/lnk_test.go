@@ -0,0 +1,59 @@
+package appm
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseShellLink(t *testing.T) {
+	t.Setenv("ProgramFiles", `C:\Program Files`)
+	t.Setenv("SystemRoot", `C:\Windows`)
+
+	tests := []struct {
+		file string
+		want ShellLink
+	}{
+		{"editor.lnk", ShellLink{
+			TargetPath:   `C:\Program Files\Editor\editor.exe`,
+			Arguments:    "--new-window",
+			WorkingDir:   `C:\Users\me`,
+			IconLocation: `C:\Windows\editor.ico`,
+			IconIndex:    2,
+			Description:  "Text editor",
+		}},
+		{"envtarget.lnk", ShellLink{
+			TargetPath:   `C:\Program Files\Tool\tool.exe`,
+			RelativePath: `..\Tool\tool.exe`,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			link, err := ReadShellLink(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *link != tt.want {
+				t.Errorf("got %+v, want %+v", *link, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseShellLinkCorrupt(t *testing.T) {
+	if _, err := ReadShellLink(filepath.Join("testdata", "truncated.lnk")); err == nil {
+		t.Error("truncated.lnk: expected an error")
+	}
+	if _, err := ParseShellLink([]byte("not a shell link")); err == nil {
+		t.Error("expected an error for data without a shell link header")
+	}
+
+	// No prefix of a valid link may crash the parser.
+	data, err := os.ReadFile(filepath.Join("testdata", "editor.lnk"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for n := range data {
+		ParseShellLink(data[:n])
+	}
+}
@@ -0,0 +1,98 @@
+package appm
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// BundleInfo is the subset of a macOS bundle's Info.plist used for listing.
+type BundleInfo struct {
+	Name       string
+	Identifier string
+	Version    string
+	Category   string
+	IconFile   string
+}
+
+// ReadBundleInfo reads Contents/Info.plist of an .app bundle.
+func ReadBundleInfo(appPath string) (*BundleInfo, error) {
+	plist, err := ReadPlist(filepath.Join(appPath, "Contents", "Info.plist"))
+	if err != nil {
+		return nil, err
+	}
+	dict, _ := plist.(map[string]any)
+	str := func(key string) string {
+		s, _ := dict[key].(string)
+		return strings.TrimSpace(s)
+	}
+
+	info := &BundleInfo{
+		Name:       str("CFBundleDisplayName"),
+		Identifier: str("CFBundleIdentifier"),
+		Version:    str("CFBundleShortVersionString"),
+		Category:   bundleCategoryName(str("LSApplicationCategoryType")),
+		IconFile:   str("CFBundleIconFile"),
+	}
+	if info.Name == "" {
+		info.Name = str("CFBundleName")
+	}
+	if info.Version == "" {
+		info.Version = str("CFBundleVersion")
+	}
+	if info.IconFile == "" {
+		info.IconFile = str("CFBundleIconName")
+	}
+	return info, nil
+}
+
+// bundleCategoryName turns a UTI such as "public.app-category.developer-tools"
+// into "Developer Tools".
+func bundleCategoryName(uti string) string {
+	name := strings.TrimPrefix(uti, "public.app-category.")
+	if name == "" || name == uti {
+		return ""
+	}
+	words := strings.Split(name, "-")
+	for i, w := range words {
+		if w != "" {
+			words[i] = strings.ToUpper(w[:1]) + w[1:]
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// bundleIconPath locates the .icns file of an .app bundle.
+func bundleIconPath(appPath string) string {
+	resources := filepath.Join(appPath, "Contents", "Resources")
+
+	names := []string{"AppIcon.icns", "icon.icns", "Application.icns"}
+	if info, err := ReadBundleInfo(appPath); err == nil && info.IconFile != "" {
+		iconName := info.IconFile
+		if !strings.HasSuffix(iconName, ".icns") {
+			iconName += ".icns"
+		}
+		names = append([]string{iconName}, names...)
+	}
+
+	for _, name := range names {
+		p := filepath.Join(resources, name)
+		if _, err := os.Stat(p); err == nil {
+			return p
+		}
+	}
+	return ""
+}
+
+// MacOSIconDataURI returns the icon of an .app bundle as a PNG data URI.
+func MacOSIconDataURI(appPath string) string {
+	icnsPath := bundleIconPath(appPath)
+	if icnsPath == "" {
+		return ""
+	}
+	uri, err := cachedIcon(icnsPath, encodeICNSFile)
+	if err != nil {
+		return ""
+	}
+	return uri
+}
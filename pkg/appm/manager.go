@@ -4,53 +4,104 @@ import (
 	"encoding/json"
 	"fmt"
 	"os/exec"
+	"rilaunch/pkg/config"
 	"runtime"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 )
 
+// Manager serves the app index to the frontend. Discovery builds a new
+// AppManager that replaces the current one once complete, so concurrent
+// readers never see a partially scanned index.
 type Manager struct {
-	appManager *AppManager
+	mu          sync.RWMutex
+	appManager  *AppManager
 	initialized bool
+	history     *LaunchHistory
+
+	// discoverMu serializes Initialize and Refresh.
+	discoverMu sync.Mutex
 }
 
 func NewManager() *Manager {
 	return &Manager{
-		appManager: NewAppManager(),
+		appManager:  NewAppManager(),
 		initialized: false,
 	}
 }
 
+// SetHistory attaches the persisted launch history used for frecency ranking.
+func (m *Manager) SetHistory(history *LaunchHistory) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.history = history
+}
+
+func (m *Manager) apps() *AppManager {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.appManager
+}
+
+func (m *Manager) isInitialized() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.initialized
+}
+
 func (m *Manager) Initialize() error {
-	if m.initialized {
+	m.discoverMu.Lock()
+	defer m.discoverMu.Unlock()
+
+	if m.isInitialized() {
 		return nil
 	}
+	return m.discover()
+}
 
+// discover scans for apps and swaps in the new index. The caller must hold
+// discoverMu.
+func (m *Manager) discover() error {
 	fmt.Println("Discovering installed applications...")
-	if err := m.appManager.DiscoverApps(); err != nil {
+	am := NewAppManager()
+	if err := am.DiscoverApps(); err != nil {
 		return fmt.Errorf("failed to discover applications: %w", err)
 	}
 
-	if len(m.appManager.GetApps()) == 0 {
-		m.addFallbackApps()
+	if am.Count() == 0 {
+		addFallbackApps(am)
 	}
+	loadCustomEntries(am)
+	m.applyLaunchHistory(am)
 
+	m.mu.Lock()
+	m.appManager = am
 	m.initialized = true
-	fmt.Printf("Discovered %d applications\n", len(m.appManager.GetApps()))
+	m.mu.Unlock()
+
+	fmt.Printf("Discovered %d applications\n", am.Count())
 	return nil
 }
 
 func (m *Manager) GetAllApps() (string, error) {
-	if !m.initialized {
+	if !m.isInitialized() {
 		if err := m.Initialize(); err != nil {
 			return "", err
 		}
 	}
 
-	apps := m.appManager.GetApps()
+	apps := m.apps().GetApps()
+	records := m.launchRecords()
+	now := time.Now()
 
-	sort.Slice(apps, func(i, j int) bool {
+	sort.SliceStable(apps, func(i, j int) bool {
+		fi := records[apps[i].ID].Frecency(now)
+		fj := records[apps[j].ID].Frecency(now)
+		if fi != fj {
+			return fi > fj
+		}
 		return strings.ToLower(apps[i].DisplayName) < strings.ToLower(apps[j].DisplayName)
 	})
 
@@ -63,35 +114,66 @@ func (m *Manager) GetAllApps() (string, error) {
 }
 
 func (m *Manager) SearchApps(query string) (string, error) {
-	if !m.initialized {
+	if !m.isInitialized() {
 		if err := m.Initialize(); err != nil {
 			return "", err
 		}
 	}
 
-	results := m.appManager.SearchApps(query)
+	// "gimp ~/shot.png" searches for "gimp" and launches it with the file,
+	// unless no app matches the first word alone.
+	var results []AppMatch
+	if appQuery, args := SplitLaunchQuery(query); args != nil {
+		if results = m.apps().SearchApps(appQuery); len(results) > 0 {
+			for i := range results {
+				results[i].Args = args
+			}
+			query = appQuery
+		}
+	}
+	if len(results) == 0 {
+		results = m.apps().SearchApps(query)
+	}
 
-	// Sort by relevance: exact matches, then starts-with, then alphabetical
-	sort.Slice(results, func(i, j int) bool {
-		queryLower := strings.ToLower(query)
-		nameI := strings.ToLower(results[i].DisplayName)
-		nameJ := strings.ToLower(results[j].DisplayName)
+	running := m.apps().RunningApps()
+	for i := range results {
+		results[i].Running = len(running[results[i].ID]) > 0
+	}
 
-		if nameI == queryLower && nameJ != queryLower {
+	// Sort by relevance: apps previously picked for this query, then exact
+	// name or alias matches, then fuzzy match score, then frecency, then alphabetical
+	records := m.launchRecords()
+	now := time.Now()
+	queryLower := strings.ToLower(query)
+	exact := func(app AppMatch) bool {
+		if strings.ToLower(app.DisplayName) == queryLower {
 			return true
 		}
-		if nameI != queryLower && nameJ == queryLower {
-			return false
+		for _, alias := range app.Aliases {
+			if strings.ToLower(alias) == queryLower {
+				return true
+			}
 		}
+		return false
+	}
 
-		if strings.HasPrefix(nameI, queryLower) && !strings.HasPrefix(nameJ, queryLower) {
-			return true
+	sort.SliceStable(results, func(i, j int) bool {
+		recI, recJ := records[results[i].ID], records[results[j].ID]
+
+		if qi, qj := recI.QueryScore(query), recJ.QueryScore(query); qi != qj {
+			return qi > qj
+		}
+		if ei, ej := exact(results[i]), exact(results[j]); ei != ej {
+			return ei
 		}
-		if !strings.HasPrefix(nameI, queryLower) && strings.HasPrefix(nameJ, queryLower) {
-			return false
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		if fi, fj := recI.Frecency(now), recJ.Frecency(now); fi != fj {
+			return fi > fj
 		}
 
-		return nameI < nameJ
+		return strings.ToLower(results[i].DisplayName) < strings.ToLower(results[j].DisplayName)
 	})
 
 	jsonData, err := json.Marshal(results)
@@ -102,42 +184,178 @@ func (m *Manager) SearchApps(query string) (string, error) {
 	return string(jsonData), nil
 }
 
-func (m *Manager) LaunchApp(appID string) error {
-	if !m.initialized {
+// LaunchApp starts the app and records the launch, together with the search
+// query it was picked for, in the launch history.
+func (m *Manager) LaunchApp(appID, query string) error {
+	if !m.isInitialized() {
+		if err := m.Initialize(); err != nil {
+			return err
+		}
+	}
+
+	// In focus mode, picking a running app raises its window instead of
+	// starting another instance, where the platform supports it.
+	if config.CurrentSettings().FocusRunningApps {
+		if err := m.apps().FocusApp(appID); err == nil {
+			m.updateLastUsed(appID, query)
+			return nil
+		}
+	}
+
+	if err := m.apps().LaunchApp(appID); err != nil {
+		return err
+	}
+
+	m.updateLastUsed(appID, query)
+	return nil
+}
+
+// LaunchAppWithArgs starts the app with the given arguments and records the
+// launch for the app part of the query.
+func (m *Manager) LaunchAppWithArgs(appID string, args []string, query string) error {
+	if !m.isInitialized() {
+		if err := m.Initialize(); err != nil {
+			return err
+		}
+	}
+
+	if err := m.apps().LaunchAppWithArgs(appID, args); err != nil {
+		return err
+	}
+
+	appQuery, _ := SplitLaunchQuery(query)
+	m.updateLastUsed(appID, appQuery)
+	return nil
+}
+
+// OpenWith returns the apps that can open target, a path or URL, as JSON,
+// with the default app first.
+func (m *Manager) OpenWith(target string) (string, error) {
+	if !m.isInitialized() {
+		if err := m.Initialize(); err != nil {
+			return "", err
+		}
+	}
+
+	jsonData, err := json.Marshal(m.apps().OpenWith(target))
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal applications: %w", err)
+	}
+	return string(jsonData), nil
+}
+
+// OpenTarget opens target with the app appID, or with the default app when
+// appID is empty. Picking an app from the index counts as a launch of it.
+func (m *Manager) OpenTarget(target, appID string) error {
+	if !m.isInitialized() {
 		if err := m.Initialize(); err != nil {
 			return err
 		}
 	}
 
-	m.updateLastUsed(appID)
+	if err := m.apps().OpenTarget(target, appID); err != nil {
+		return err
+	}
 
-	return m.appManager.LaunchApp(appID)
+	if _, ok := m.apps().GetApp(appID); ok {
+		m.updateLastUsed(appID, "")
+	}
+	return nil
 }
 
-func (m *Manager) updateLastUsed(appID string) {
-	apps := m.appManager.GetApps()
-	for i, app := range apps {
-		if app.ID == appID {
-			apps[i].LastUsed = time.Now()
-			break
+// LaunchAppAction starts one of the app's actions. The launch counts towards
+// the parent app's history.
+func (m *Manager) LaunchAppAction(appID, actionID, query string) error {
+	if !m.isInitialized() {
+		if err := m.Initialize(); err != nil {
+			return err
 		}
 	}
+
+	if err := m.apps().LaunchAppAction(appID, actionID); err != nil {
+		return err
+	}
+
+	m.updateLastUsed(appID, query)
+	return nil
+}
+
+func (m *Manager) updateLastUsed(appID, query string) {
+	now := time.Now()
+	m.apps().setLastUsed(map[string]time.Time{appID: now})
+
+	if history := m.launchHistory(); history != nil {
+		if err := history.Record(appID, query, now); err != nil {
+			fmt.Printf("Warning: failed to record launch of %s: %v\n", appID, err)
+		}
+	}
+}
+
+func (m *Manager) launchHistory() *LaunchHistory {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.history
+}
+
+func (m *Manager) launchRecords() map[string]LaunchRecord {
+	history := m.launchHistory()
+	if history == nil {
+		return nil
+	}
+	records, err := history.ReadAll()
+	if err != nil {
+		fmt.Printf("Warning: failed to read launch history: %v\n", err)
+		return nil
+	}
+	return records
+}
+
+func (m *Manager) applyLaunchHistory(am *AppManager) {
+	times := make(map[string]time.Time)
+	for id, record := range m.launchRecords() {
+		times[id] = record.LastUsed()
+	}
+	am.setLastUsed(times)
+}
+
+// GetApp returns the app with the given ID.
+func (m *Manager) GetApp(appID string) (AppInfo, bool) {
+	return m.apps().GetApp(appID)
 }
 
 func (m *Manager) GetAppCount() int {
-	if !m.initialized {
+	if !m.isInitialized() {
 		return 0
 	}
-	return len(m.appManager.GetApps())
+	return m.apps().Count()
 }
 
+// Refresh rediscovers all apps. The current index keeps being served until
+// the new one is complete.
 func (m *Manager) Refresh() error {
-	m.initialized = false
-	m.appManager = NewAppManager()
-	return m.Initialize()
+	m.discoverMu.Lock()
+	defer m.discoverMu.Unlock()
+	return m.discover()
+}
+
+// loadCustomEntries merges the custom entries file into the index.
+func loadCustomEntries(am *AppManager) {
+	c, err := LoadCustomConfig()
+	if err != nil {
+		fmt.Printf("Warning: failed to load custom entries: %v\n", err)
+		return
+	}
+	am.SetCustomConfig(c, CustomConfigPath())
+}
+
+// ReloadCustomEntries re-reads the custom entries file after it was edited.
+func (m *Manager) ReloadCustomEntries() {
+	am := m.apps()
+	loadCustomEntries(am)
+	m.applyLaunchHistory(am)
 }
 
-func (m *Manager) addFallbackApps() {
+func addFallbackApps(am *AppManager) {
 	fallbackApps := []AppInfo{
 		{
 			ID:          "terminal",
@@ -183,7 +401,7 @@ func (m *Manager) addFallbackApps() {
 
 	for _, app := range fallbackApps {
 		if app.Path != "" {
-			m.appManager.AddApp(app)
+			am.AddApp(app)
 		}
 	}
 }
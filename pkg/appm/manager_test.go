@@ -0,0 +1,87 @@
+package appm
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func writeDesktopFile(t *testing.T, dir, id, name string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	entry := fmt.Sprintf("[Desktop Entry]\nType=Application\nName=%s\nExec=/usr/bin/%s\n", name, id)
+	if err := os.WriteFile(filepath.Join(dir, id+".desktop"), []byte(entry), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestManagerConcurrentAccess reads the index while it is being rebuilt and
+// modified. Run it with -race.
+func TestManagerConcurrentAccess(t *testing.T) {
+	dataHome, dataDir := setAppDirs(t)
+	for i, name := range []string{"GIMP", "Visual Studio Code", "Firefox", "Files", "Terminal"} {
+		writeDesktopFile(t, filepath.Join(dataDir, "applications"), fmt.Sprintf("app%d", i), name)
+	}
+	writeDesktopFile(t, filepath.Join(dataHome, "applications"), "app0", "GIMP (user)")
+
+	m := NewManager()
+	const rounds = 200
+	var wg sync.WaitGroup
+	run := func(f func(i int)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range rounds {
+				f(i)
+			}
+		}()
+	}
+
+	run(func(int) {
+		if err := m.Initialize(); err != nil {
+			t.Error(err)
+		}
+	})
+	run(func(int) {
+		if err := m.Refresh(); err != nil {
+			t.Error(err)
+		}
+	})
+	run(func(i int) {
+		m.apps().AddApp(AppInfo{ID: fmt.Sprintf("extra%d", i), DisplayName: "Extra", Source: "/opt/extra"})
+	})
+	run(func(int) {
+		m.apps().removeApps(func(app AppInfo) bool { return app.DisplayName == "Extra" })
+	})
+	run(func(int) {
+		if _, err := m.SearchApps("gmp"); err != nil {
+			t.Error(err)
+		}
+	})
+	run(func(int) {
+		if _, err := m.GetAllApps(); err != nil {
+			t.Error(err)
+		}
+		for _, app := range m.apps().GetApps() {
+			app.Keywords = append(app.Keywords, "copy")
+		}
+	})
+	run(func(int) {
+		if app, ok := m.GetApp("app0.desktop"); ok && app.DisplayName != "GIMP (user)" {
+			t.Errorf("GetApp(app0.desktop) = %q, want the user's entry", app.DisplayName)
+		}
+		m.GetAppCount()
+	})
+	wg.Wait()
+
+	if n := m.GetAppCount(); n < 5 {
+		t.Errorf("GetAppCount() = %d, want at least 5", n)
+	}
+	if _, ok := m.GetApp("app0.desktop"); !ok {
+		t.Error("GetApp(app0.desktop) not found")
+	}
+}
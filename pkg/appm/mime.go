@@ -0,0 +1,378 @@
+package appm
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"mime"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"unicode/utf8"
+)
+
+// MIME associations follow the Association between MIME types and
+// applications spec, as used by xdg-open:
+// https://specifications.freedesktop.org/mime-apps-spec/latest/
+
+const (
+	mimeDefaultGroup = "Default Applications"
+	mimeAddedGroup   = "Added Associations"
+	mimeRemovedGroup = "Removed Associations"
+	mimeCacheGroup   = "MIME Cache"
+)
+
+// OpenWithResult lists the apps that can open a target, the default first.
+type OpenWithResult struct {
+	Target   string    `json:"target"`
+	MimeType string    `json:"mimeType"`
+	Default  string    `json:"default,omitempty"`
+	Apps     []AppInfo `json:"apps"`
+}
+
+// TargetMimeType returns the MIME type of a path or URL: x-scheme-handler/<scheme>
+// for URLs, inode/directory for directories, and otherwise the type of the
+// file's extension or, failing that, of its content.
+func TargetMimeType(target string) string {
+	if scheme := urlScheme(target); scheme != "" && scheme != "file" {
+		return "x-scheme-handler/" + scheme
+	}
+	path := targetPath(target)
+
+	info, err := os.Stat(path)
+	if err == nil && info.IsDir() {
+		return "inode/directory"
+	}
+	if t := mime.TypeByExtension(strings.ToLower(filepath.Ext(path))); t != "" {
+		t, _, _ = strings.Cut(t, ";")
+		return strings.TrimSpace(t)
+	}
+	if err != nil {
+		return "application/octet-stream"
+	}
+	return sniffMimeType(path)
+}
+
+// urlScheme returns the lower-case scheme of a URL, or "" for paths,
+// including Windows paths such as C:\Users.
+func urlScheme(target string) string {
+	u, err := url.Parse(target)
+	if err != nil || len(u.Scheme) < 2 {
+		return ""
+	}
+	if !strings.Contains(target, "://") && u.Opaque == "" {
+		return ""
+	}
+	return strings.ToLower(u.Scheme)
+}
+
+// targetPath turns file:// URLs into paths and resolves typed paths the way
+// launch arguments are resolved.
+func targetPath(target string) string {
+	if urlScheme(target) == "file" {
+		if u, err := url.Parse(target); err == nil {
+			return u.Path
+		}
+	}
+	return resolveLaunchArg(target)
+}
+
+// sniffMimeType guesses the type of a file without a known extension from
+// its first bytes.
+func sniffMimeType(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return "application/octet-stream"
+	}
+	defer f.Close()
+
+	head := make([]byte, 512)
+	n, _ := f.Read(head)
+	head = head[:n]
+	switch {
+	case n == 0:
+		return "application/x-zerosize"
+	case bytes.HasPrefix(head, []byte("\x7fELF")):
+		return "application/x-executable"
+	case bytes.HasPrefix(head, []byte("#!")):
+		return "application/x-shellscript"
+	case bytes.IndexByte(head, 0) < 0 && (utf8.Valid(head) || n == 512 && utf8.Valid(head[:n-utf8.UTFMax])):
+		return "text/plain"
+	}
+	return "application/octet-stream"
+}
+
+// mimeTypeChain returns the type followed by its canonical name, if it is
+// an alias, and its parent types from the shared MIME database, so a
+// text/x-python file can be opened by apps handling text/plain.
+func mimeTypeChain(mimeType string) []string {
+	aliases := readMimeDBPairs("aliases")
+	parents := make(map[string][]string)
+	for _, pair := range readMimeDBPairsList("subclasses") {
+		parents[pair[0]] = append(parents[pair[0]], pair[1])
+	}
+
+	var chain []string
+	seen := make(map[string]bool)
+	queue := []string{mimeType}
+	for len(queue) > 0 {
+		t := queue[0]
+		queue = queue[1:]
+		if canonical, ok := aliases[t]; ok {
+			t = canonical
+		}
+		if seen[t] {
+			continue
+		}
+		seen[t] = true
+		chain = append(chain, t)
+		queue = append(queue, parents[t]...)
+		if strings.HasPrefix(t, "text/") && t != "text/plain" {
+			queue = append(queue, "text/plain")
+		}
+	}
+	return chain
+}
+
+func readMimeDBPairs(name string) map[string]string {
+	pairs := make(map[string]string)
+	for _, pair := range readMimeDBPairsList(name) {
+		if _, ok := pairs[pair[0]]; !ok {
+			pairs[pair[0]] = pair[1]
+		}
+	}
+	return pairs
+}
+
+// readMimeDBPairsList reads a "type other-type" file of the shared MIME
+// database, such as mime/subclasses, from all data directories.
+func readMimeDBPairsList(name string) [][2]string {
+	var pairs [][2]string
+	for _, dir := range xdgDataDirs() {
+		f, err := os.Open(filepath.Join(dir, "mime", name))
+		if err != nil {
+			continue
+		}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) == 2 && !strings.HasPrefix(fields[0], "#") {
+				pairs = append(pairs, [2]string{fields[0], fields[1]})
+			}
+		}
+		f.Close()
+	}
+	return pairs
+}
+
+// mimeAppsLists returns the mimeapps.list files in precedence order, each
+// directory's desktop-specific files before its generic one.
+func mimeAppsLists() []string {
+	home := os.Getenv("HOME")
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		configHome = filepath.Join(home, ".config")
+	}
+	configDirs := os.Getenv("XDG_CONFIG_DIRS")
+	if configDirs == "" {
+		configDirs = "/etc/xdg"
+	}
+
+	dirs := append([]string{configHome}, filepath.SplitList(configDirs)...)
+	dirs = append(dirs, desktopAppDirs()...)
+
+	var names []string
+	for _, desktop := range currentDesktops() {
+		names = append(names, strings.ToLower(desktop)+"-mimeapps.list")
+	}
+	names = append(names, "mimeapps.list")
+
+	var lists []string
+	for _, dir := range dirs {
+		if dir == "" || !filepath.IsAbs(dir) {
+			continue
+		}
+		for _, name := range names {
+			lists = append(lists, filepath.Join(dir, name))
+		}
+	}
+	return lists
+}
+
+// mimeAssociations holds the desktop file IDs associated with a MIME type.
+type mimeAssociations struct {
+	defaults []string
+	// added and removed are per mimeapps.list, in precedence order; a
+	// removal hides the associations of its own and lower precedence files.
+	added   [][]string
+	removed [][]string
+	// cached is the apps listing the type in mimeinfo.cache, in
+	// applications directory order.
+	cached []string
+}
+
+func loadMimeAssociations(mimeType string) mimeAssociations {
+	var assoc mimeAssociations
+	for _, path := range mimeAppsLists() {
+		df, err := readKeyFile(path)
+		if err != nil {
+			continue
+		}
+		assoc.defaults = append(assoc.defaults, df.Strings(mimeDefaultGroup, mimeType)...)
+		assoc.added = append(assoc.added, df.Strings(mimeAddedGroup, mimeType))
+		assoc.removed = append(assoc.removed, df.Strings(mimeRemovedGroup, mimeType))
+	}
+	for _, dir := range desktopAppDirs() {
+		if df, err := readKeyFile(filepath.Join(dir, "mimeinfo.cache")); err == nil {
+			assoc.cached = append(assoc.cached, df.Strings(mimeCacheGroup, mimeType)...)
+		}
+	}
+	return assoc
+}
+
+func readKeyFile(path string) (*DesktopFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseKeyFile(f)
+}
+
+// removedFrom reports whether a file at precedence level, or one before it,
+// removes the association with id.
+func (assoc mimeAssociations) removedFrom(level int, id string) bool {
+	for i := 0; i <= level && i < len(assoc.removed); i++ {
+		for _, removed := range assoc.removed[i] {
+			if removed == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// mimeHandler returns the app of a desktop file ID. Handlers need not be
+// shown in the launcher, so entries hidden with NoDisplay are parsed from
+// their desktop file when they are not in the index.
+func (am *AppManager) mimeHandler(id string) (AppInfo, bool) {
+	if app, ok := am.GetApp(id); ok {
+		return app, true
+	}
+	path := resolveDesktopID(id)
+	if path == "" {
+		return AppInfo{}, false
+	}
+	df, err := ParseDesktopFile(path)
+	if err != nil || df.Bool(desktopEntryGroup, "Hidden") {
+		return AppInfo{}, false
+	}
+	if t := df.String(desktopEntryGroup, "Type"); t != "Application" && t != "" {
+		return AppInfo{}, false
+	}
+	app := desktopApp(df, path)
+	app.ID = id
+	if app.Path == "" {
+		return AppInfo{}, false
+	}
+	return *app, true
+}
+
+// OpenWith lists the apps that can open target, a path or URL, with the
+// default app first. Associations are only known on Linux; elsewhere the
+// list is empty and OpenTarget leaves the choice to the system.
+func (am *AppManager) OpenWith(target string) OpenWithResult {
+	mimeType := TargetMimeType(target)
+	result := OpenWithResult{Target: target, MimeType: mimeType, Apps: []AppInfo{}}
+	if runtime.GOOS != "linux" {
+		return result
+	}
+
+	seen := make(map[string]bool)
+	add := func(id string) {
+		if seen[id] {
+			return
+		}
+		seen[id] = true
+		if app, ok := am.mimeHandler(id); ok {
+			result.Apps = append(result.Apps, app)
+		}
+	}
+
+	chain := mimeTypeChain(mimeType)
+	assocs := make([]mimeAssociations, len(chain))
+	for i, t := range chain {
+		assocs[i] = loadMimeAssociations(t)
+	}
+
+	// The first installed default of the most specific type wins, even over
+	// apps associated with the type itself, as with gio.
+	for _, assoc := range assocs {
+		for _, id := range assoc.defaults {
+			if _, ok := am.mimeHandler(id); ok && result.Default == "" {
+				result.Default = id
+			}
+		}
+	}
+	if result.Default != "" {
+		add(result.Default)
+	}
+
+	for i, assoc := range assocs {
+		for level, ids := range assoc.added {
+			for _, id := range ids {
+				if !assoc.removedFrom(level, id) {
+					add(id)
+				}
+			}
+		}
+		last := len(assoc.removed) - 1
+		for _, id := range assoc.cached {
+			if !assoc.removedFrom(last, id) {
+				add(id)
+			}
+		}
+		// mimeinfo.cache may be stale; the index knows the MimeType keys.
+		for _, app := range am.GetApps() {
+			if containsString(app.MimeTypes, chain[i]) && !assoc.removedFrom(last, app.ID) {
+				add(app.ID)
+			}
+		}
+	}
+
+	if result.Default == "" && len(result.Apps) > 0 {
+		result.Default = result.Apps[0].ID
+	}
+	return result
+}
+
+func containsString(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
+
+// OpenTarget opens target with the app appID, or with the default app when
+// appID is empty. Without a known default, the system's opener decides.
+func (am *AppManager) OpenTarget(target, appID string) error {
+	if appID == "" {
+		appID = am.OpenWith(target).Default
+	}
+	if appID == "" {
+		return openWithDefault(targetPath(target))
+	}
+
+	app, ok := am.mimeHandler(appID)
+	if !ok {
+		return fmt.Errorf("application not found: %s", appID)
+	}
+	if urlScheme(target) == "" {
+		target = targetPath(target)
+	}
+	return am.launchWithArgs(app, []string{target})
+}
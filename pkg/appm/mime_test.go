@@ -0,0 +1,111 @@
+package appm
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// TestOpenWithPrecedence checks how the mimeapps.list files of the user,
+// the system and the desktop combine with mimeinfo.cache.
+func TestOpenWithPrecedence(t *testing.T) {
+	const mimeType = "x-scheme-handler/palproto"
+	list := func(groups ...string) string {
+		var s string
+		for i := 0; i+1 < len(groups); i += 2 {
+			s += "[" + groups[i] + "]\n" + mimeType + "=" + groups[i+1] + "\n"
+		}
+		return s
+	}
+
+	tests := []struct {
+		name        string
+		user        string // $XDG_CONFIG_HOME/mimeapps.list
+		userDesktop string // $XDG_CONFIG_HOME/gnome-mimeapps.list
+		system      string // $XDG_CONFIG_DIRS/mimeapps.list
+		wantDefault string
+		wantApps    []string
+	}{
+		{name: "cache only",
+			wantDefault: "e.desktop", wantApps: []string{"e.desktop", "d.desktop"}},
+		{name: "system default",
+			system:      list(mimeDefaultGroup, "b.desktop;"),
+			wantDefault: "b.desktop", wantApps: []string{"b.desktop", "e.desktop", "d.desktop"}},
+		{name: "user default over system default",
+			user:        list(mimeDefaultGroup, "a.desktop;"),
+			system:      list(mimeDefaultGroup, "b.desktop;"),
+			wantDefault: "a.desktop", wantApps: []string{"a.desktop", "e.desktop", "d.desktop"}},
+		{name: "uninstalled user default",
+			user:        list(mimeDefaultGroup, "missing.desktop;"),
+			system:      list(mimeDefaultGroup, "b.desktop;"),
+			wantDefault: "b.desktop", wantApps: []string{"b.desktop", "e.desktop", "d.desktop"}},
+		{name: "desktop-specific over generic",
+			user:        list(mimeDefaultGroup, "a.desktop;"),
+			userDesktop: list(mimeDefaultGroup, "c.desktop;"),
+			wantDefault: "c.desktop", wantApps: []string{"c.desktop", "e.desktop", "d.desktop"}},
+		{name: "added before cached",
+			user:        list(mimeAddedGroup, "c.desktop;"),
+			system:      list(mimeAddedGroup, "b.desktop;"),
+			wantDefault: "c.desktop", wantApps: []string{"c.desktop", "b.desktop", "e.desktop", "d.desktop"}},
+		{name: "removed hides cached",
+			user:        list(mimeRemovedGroup, "e.desktop;"),
+			wantDefault: "d.desktop", wantApps: []string{"d.desktop"}},
+		{name: "user removal hides system addition",
+			user:        list(mimeRemovedGroup, "b.desktop;"),
+			system:      list(mimeAddedGroup, "b.desktop;"),
+			wantDefault: "e.desktop", wantApps: []string{"e.desktop", "d.desktop"}},
+		{name: "system removal keeps user addition",
+			user:        list(mimeAddedGroup, "b.desktop;"),
+			system:      list(mimeRemovedGroup, "b.desktop;"),
+			wantDefault: "b.desktop", wantApps: []string{"b.desktop", "e.desktop", "d.desktop"}},
+		{name: "removal and addition in one file",
+			user:        list(mimeAddedGroup, "b.desktop;", mimeRemovedGroup, "b.desktop;d.desktop;"),
+			wantDefault: "e.desktop", wantApps: []string{"e.desktop"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, dataDir := setAppDirs(t)
+			home := os.Getenv("HOME")
+			configHome := filepath.Join(home, ".config")
+			configDir := filepath.Join(home, "etc", "xdg")
+			t.Setenv("XDG_CONFIG_HOME", configHome)
+			t.Setenv("XDG_CONFIG_DIRS", configDir)
+			t.Setenv("XDG_CURRENT_DESKTOP", "GNOME")
+
+			apps := filepath.Join(dataDir, "applications")
+			for _, id := range []string{"a", "b", "c", "d", "e"} {
+				writeDesktopFile(t, apps, id, id)
+			}
+			files := map[string]string{
+				filepath.Join(apps, "mimeinfo.cache"):            list(mimeCacheGroup, "e.desktop;d.desktop;"),
+				filepath.Join(configHome, "mimeapps.list"):       tt.user,
+				filepath.Join(configHome, "gnome-mimeapps.list"): tt.userDesktop,
+				filepath.Join(configDir, "mimeapps.list"):        tt.system,
+			}
+			for path, content := range files {
+				if content == "" {
+					continue
+				}
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			result := NewAppManager().OpenWith("palproto://item")
+			if result.MimeType != mimeType {
+				t.Fatalf("MimeType = %q, want %q", result.MimeType, mimeType)
+			}
+			var ids []string
+			for _, app := range result.Apps {
+				ids = append(ids, app.ID)
+			}
+			if result.Default != tt.wantDefault || !slices.Equal(ids, tt.wantApps) {
+				t.Errorf("OpenWith = %q, %q, want %q, %q", result.Default, ids, tt.wantDefault, tt.wantApps)
+			}
+		})
+	}
+}
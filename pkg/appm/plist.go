@@ -0,0 +1,337 @@
+package appm
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// ReadPlist parses an XML or binary property list file. Dictionaries decode
+// to map[string]any, arrays to []any, and scalars to string, int64, float64,
+// bool, time.Time or []byte.
+func ReadPlist(path string) (any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePlist(data)
+}
+
+func ParsePlist(data []byte) (any, error) {
+	if bytes.HasPrefix(data, []byte("bplist00")) {
+		return parseBinaryPlist(data)
+	}
+	return parseXMLPlist(data)
+}
+
+// ── XML ───────────────────────────────────────────────────────────────────────
+
+func parseXMLPlist(data []byte) (any, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.Strict = false
+
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("plist: %w", err)
+		}
+		if start, ok := tok.(xml.StartElement); ok && start.Name.Local != "plist" {
+			return parseXMLValue(dec, start)
+		}
+	}
+}
+
+func parseXMLValue(dec *xml.Decoder, start xml.StartElement) (any, error) {
+	switch start.Name.Local {
+	case "dict":
+		dict := make(map[string]any)
+		var key string
+		for {
+			tok, err := dec.Token()
+			if err != nil {
+				return nil, fmt.Errorf("plist: %w", err)
+			}
+			switch t := tok.(type) {
+			case xml.StartElement:
+				if t.Name.Local == "key" {
+					if key, err = xmlText(dec); err != nil {
+						return nil, err
+					}
+					continue
+				}
+				v, err := parseXMLValue(dec, t)
+				if err != nil {
+					return nil, err
+				}
+				dict[key] = v
+			case xml.EndElement:
+				return dict, nil
+			}
+		}
+	case "array":
+		var array []any
+		for {
+			tok, err := dec.Token()
+			if err != nil {
+				return nil, fmt.Errorf("plist: %w", err)
+			}
+			switch t := tok.(type) {
+			case xml.StartElement:
+				v, err := parseXMLValue(dec, t)
+				if err != nil {
+					return nil, err
+				}
+				array = append(array, v)
+			case xml.EndElement:
+				return array, nil
+			}
+		}
+	case "true", "false":
+		if err := dec.Skip(); err != nil {
+			return nil, err
+		}
+		return start.Name.Local == "true", nil
+	}
+
+	text, err := xmlText(dec)
+	if err != nil {
+		return nil, err
+	}
+	switch start.Name.Local {
+	case "string":
+		return text, nil
+	case "integer":
+		return strconv.ParseInt(strings.TrimSpace(text), 10, 64)
+	case "real":
+		return strconv.ParseFloat(strings.TrimSpace(text), 64)
+	case "date":
+		return time.Parse(time.RFC3339, strings.TrimSpace(text))
+	case "data":
+		return base64.StdEncoding.DecodeString(strings.Join(strings.Fields(text), ""))
+	default:
+		return nil, fmt.Errorf("plist: unknown element <%s>", start.Name.Local)
+	}
+}
+
+// xmlText reads character data up to the end of the current element.
+func xmlText(dec *xml.Decoder) (string, error) {
+	var b strings.Builder
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return "", fmt.Errorf("plist: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.CharData:
+			b.Write(t)
+		case xml.EndElement:
+			return b.String(), nil
+		}
+	}
+}
+
+// ── Binary ────────────────────────────────────────────────────────────────────
+
+type binaryPlist struct {
+	data    []byte
+	offsets []uint64
+	refSize int
+	depth   int
+}
+
+func parseBinaryPlist(data []byte) (any, error) {
+	if len(data) < 8+32 {
+		return nil, fmt.Errorf("plist: binary plist too short")
+	}
+	trailer := data[len(data)-32:]
+	offsetSize := int(trailer[6])
+	refSize := int(trailer[7])
+	numObjects := binary.BigEndian.Uint64(trailer[8:16])
+	topObject := binary.BigEndian.Uint64(trailer[16:24])
+	tableOffset := binary.BigEndian.Uint64(trailer[24:32])
+
+	if offsetSize < 1 || offsetSize > 8 || refSize < 1 || refSize > 8 {
+		return nil, fmt.Errorf("plist: invalid trailer")
+	}
+	// Bound each term before multiplying, so crafted values cannot overflow.
+	tableEnd := uint64(len(data) - 32)
+	if tableOffset >= tableEnd || numObjects > (tableEnd-tableOffset)/uint64(offsetSize) {
+		return nil, fmt.Errorf("plist: offset table out of range")
+	}
+
+	p := &binaryPlist{data: data, refSize: refSize, offsets: make([]uint64, numObjects)}
+	for i := range p.offsets {
+		start := tableOffset + uint64(i)*uint64(offsetSize)
+		p.offsets[i] = readUint(data[start : start+uint64(offsetSize)])
+	}
+	return p.object(topObject)
+}
+
+func readUint(b []byte) uint64 {
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
+
+func (p *binaryPlist) object(ref uint64) (any, error) {
+	if ref >= uint64(len(p.offsets)) {
+		return nil, fmt.Errorf("plist: object reference %d out of range", ref)
+	}
+	// Guard against reference cycles in malformed files.
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.depth > 512 {
+		return nil, fmt.Errorf("plist: nesting too deep")
+	}
+
+	off := p.offsets[ref]
+	if off >= uint64(len(p.data)) {
+		return nil, fmt.Errorf("plist: object offset out of range")
+	}
+	marker := p.data[off]
+	kind, info := marker>>4, int(marker&0x0f)
+	body := p.data[off+1:]
+
+	switch kind {
+	case 0x0:
+		switch marker {
+		case 0x08:
+			return false, nil
+		case 0x09:
+			return true, nil
+		}
+		return nil, nil
+	case 0x1:
+		n := 1 << info
+		if n > len(body) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return int64(readUint(body[:n])), nil
+	case 0x2:
+		n := 1 << info
+		if n != 4 && n != 8 {
+			return nil, fmt.Errorf("plist: invalid real size %d", n)
+		}
+		if n > len(body) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		if n == 4 {
+			return float64(math.Float32frombits(uint32(readUint(body[:4])))), nil
+		}
+		return math.Float64frombits(readUint(body[:8])), nil
+	case 0x3:
+		if len(body) < 8 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		secs := math.Float64frombits(readUint(body[:8]))
+		// Binary plist dates count seconds from 2001-01-01.
+		return time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(secs * float64(time.Second))), nil
+	}
+
+	count, body, err := p.length(info, body)
+	if err != nil {
+		return nil, err
+	}
+
+	switch kind {
+	case 0x4:
+		if count > len(body) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return append([]byte(nil), body[:count]...), nil
+	case 0x5:
+		if count > len(body) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return string(body[:count]), nil
+	case 0x6:
+		if count*2 > len(body) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		units := make([]uint16, count)
+		for i := range units {
+			units[i] = binary.BigEndian.Uint16(body[i*2:])
+		}
+		return string(utf16.Decode(units)), nil
+	case 0x8:
+		return int64(readUint(body[:min(info+1, len(body))])), nil
+	case 0xA:
+		refs, err := p.refs(body, count)
+		if err != nil {
+			return nil, err
+		}
+		array := make([]any, count)
+		for i, r := range refs {
+			if array[i], err = p.object(r); err != nil {
+				return nil, err
+			}
+		}
+		return array, nil
+	case 0xD:
+		refs, err := p.refs(body, count*2)
+		if err != nil {
+			return nil, err
+		}
+		dict := make(map[string]any, count)
+		for i := 0; i < count; i++ {
+			k, err := p.object(refs[i])
+			if err != nil {
+				return nil, err
+			}
+			key, ok := k.(string)
+			if !ok {
+				return nil, fmt.Errorf("plist: non-string dictionary key")
+			}
+			if dict[key], err = p.object(refs[count+i]); err != nil {
+				return nil, err
+			}
+		}
+		return dict, nil
+	}
+	return nil, fmt.Errorf("plist: unsupported object type 0x%x", kind)
+}
+
+// length decodes an object's element count, stored in the marker or, when
+// the marker holds 0xF, in a following integer object.
+func (p *binaryPlist) length(info int, body []byte) (int, []byte, error) {
+	if info != 0x0f {
+		return info, body, nil
+	}
+	if len(body) < 1 || body[0]>>4 != 0x1 {
+		return 0, nil, fmt.Errorf("plist: invalid length marker")
+	}
+	n := 1 << (body[0] & 0x0f)
+	if 1+n > len(body) {
+		return 0, nil, io.ErrUnexpectedEOF
+	}
+	count := readUint(body[1 : 1+n])
+	if count > uint64(len(p.data)) {
+		return 0, nil, fmt.Errorf("plist: length out of range")
+	}
+	return int(count), body[1+n:], nil
+}
+
+func (p *binaryPlist) refs(body []byte, count int) ([]uint64, error) {
+	if count*p.refSize > len(body) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	refs := make([]uint64, count)
+	for i := range refs {
+		refs[i] = readUint(body[i*p.refSize : (i+1)*p.refSize])
+		if refs[i] >= uint64(len(p.offsets)) {
+			return nil, fmt.Errorf("plist: object reference %d out of range", refs[i])
+		}
+	}
+	return refs, nil
+}
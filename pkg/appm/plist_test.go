@@ -0,0 +1,69 @@
+package appm
+
+import (
+	"encoding/binary"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadPlist(t *testing.T) {
+	want := map[string]any{
+		"CFBundleName":              "Editor",
+		"CFBundleExecutable":        "editor",
+		"CFBundleIdentifier":        "com.example.editor",
+		"CFBundleIconFile":          "Editor.icns",
+		"LSApplicationCategoryType": "public.app-category.developer-tools",
+		"CFBundleDocumentTypes": []any{
+			map[string]any{"CFBundleTypeExtensions": []any{"txt", "md"}},
+		},
+		"LSUIElement":             false,
+		"NSHighResolutionCapable": true,
+		"LSMinimumSystemVersion":  "10.13",
+		"BuildNumber":             int64(42),
+		"Scale":                   1.5,
+	}
+	for _, file := range []string{"Info.plist", "Info.bplist"} {
+		t.Run(file, func(t *testing.T) {
+			got, err := ReadPlist(filepath.Join("testdata", file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %#v, want %#v", got, want)
+			}
+		})
+	}
+}
+
+// binaryPlistTrailer builds a minimal binary plist whose trailer holds the
+// given offset table description.
+func binaryPlistTrailer(offsetSize, refSize byte, numObjects, topObject, tableOffset uint64) []byte {
+	data := []byte("bplist00\x08\x08")
+	trailer := make([]byte, 32)
+	trailer[6], trailer[7] = offsetSize, refSize
+	binary.BigEndian.PutUint64(trailer[8:], numObjects)
+	binary.BigEndian.PutUint64(trailer[16:], topObject)
+	binary.BigEndian.PutUint64(trailer[24:], tableOffset)
+	return append(data, trailer...)
+}
+
+func TestParseBinaryPlistCorrupt(t *testing.T) {
+	tests := map[string][]byte{
+		"short":                    []byte("bplist00"),
+		"table offset overflows":   binaryPlistTrailer(8, 1, 2, 0, ^uint64(0)-7),
+		"object count overflows":   binaryPlistTrailer(8, 1, 1<<61, 0, 9),
+		"table past the end":       binaryPlistTrailer(1, 1, 2, 0, 9),
+		"top object out of range":  binaryPlistTrailer(1, 1, 1, 5, 9),
+		"invalid offset size":      binaryPlistTrailer(0, 1, 1, 0, 9),
+		"array ref out of range":   append([]byte("bplist00\xa1\x07\x08"), binaryPlistTrailer(1, 1, 1, 0, 10)[10:]...),
+		"real of unsupported size": append([]byte("bplist00\x21\x00\x08"), binaryPlistTrailer(1, 1, 1, 0, 10)[10:]...),
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			if v, err := ParsePlist(data); err == nil {
+				t.Errorf("expected an error, got %#v", v)
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package appm
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"rilaunch/pkg/x11"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var errAppNotRunning = errors.New("app is not running")
+
+// runningCacheTTL is how long a scan of the running processes is reused, so
+// typing a query does not rescan /proc on every keystroke.
+const runningCacheTTL = 2 * time.Second
+
+// execWrappers are programs that run the actual app, so their name says
+// nothing about which app a process belongs to.
+var execWrappers = map[string]bool{
+	"env": true, "flatpak": true, "snap": true, "sh": true, "bash": true,
+	"python": true, "python3": true, "java": true, "gio": true, "xdg-open": true,
+}
+
+// processTracker remembers the processes rilaunch started, per app ID,
+// until they exit.
+type processTracker struct {
+	mu   sync.Mutex
+	pids map[string]map[int]bool
+}
+
+// launched survives index refreshes, which replace the AppManager.
+var launched = &processTracker{pids: make(map[string]map[int]bool)}
+
+func (t *processTracker) add(appID string, pid int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.pids[appID] == nil {
+		t.pids[appID] = make(map[int]bool)
+	}
+	t.pids[appID][pid] = true
+}
+
+func (t *processTracker) remove(appID string, pid int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.pids[appID], pid)
+	if len(t.pids[appID]) == 0 {
+		delete(t.pids, appID)
+	}
+}
+
+func (t *processTracker) snapshot() map[string][]int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	pids := make(map[string][]int, len(t.pids))
+	for appID, set := range t.pids {
+		for pid := range set {
+			pids[appID] = append(pids[appID], pid)
+		}
+	}
+	return pids
+}
+
+// process is a running process and the lower-case names it can be
+// recognized by: its comm, and the base names of argv[0] and its executable.
+type process struct {
+	pid   int
+	names []string
+}
+
+// listProcesses reads the processes of the current user from /proc. Other
+// platforms report none.
+func listProcesses() []process {
+	if runtime.GOOS != "linux" {
+		return nil
+	}
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil
+	}
+
+	uid := os.Getuid()
+	var procs []process
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		dir := filepath.Join("/proc", entry.Name())
+		if !ownedBy(dir, uid) {
+			continue
+		}
+
+		var names []string
+		if comm, err := os.ReadFile(filepath.Join(dir, "comm")); err == nil {
+			names = append(names, strings.ToLower(strings.TrimSpace(string(comm))))
+		}
+		if cmdline, err := os.ReadFile(filepath.Join(dir, "cmdline")); err == nil && len(cmdline) > 0 {
+			argv0, _, _ := bytes.Cut(cmdline, []byte{0})
+			names = append(names, strings.ToLower(filepath.Base(string(argv0))))
+		}
+		if exe, err := os.Readlink(filepath.Join(dir, "exe")); err == nil {
+			names = append(names, strings.ToLower(filepath.Base(strings.TrimSuffix(exe, " (deleted)"))))
+		}
+		if len(names) > 0 {
+			procs = append(procs, process{pid: pid, names: names})
+		}
+	}
+	return procs
+}
+
+// ownedBy reports whether the process in the /proc directory runs as uid.
+func ownedBy(dir string, uid int) bool {
+	status, err := os.ReadFile(filepath.Join(dir, "status"))
+	if err != nil {
+		return false
+	}
+	for _, line := range strings.Split(string(status), "\n") {
+		if rest, ok := strings.CutPrefix(line, "Uid:"); ok {
+			fields := strings.Fields(rest)
+			return len(fields) > 0 && fields[0] == strconv.Itoa(uid)
+		}
+	}
+	return false
+}
+
+// execName returns the lower-case name of the program an app runs, skipping
+// env and its variable assignments. It is empty for apps started through a
+// wrapper such as flatpak, whose processes are recognized by their window
+// class only.
+func execName(app AppInfo) string {
+	args, err := splitExec(app.Path)
+	if err != nil {
+		return ""
+	}
+	for len(args) > 0 && (args[0] == "env" || strings.Contains(args[0], "=")) {
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		return ""
+	}
+	name := strings.ToLower(filepath.Base(args[0]))
+	if execWrappers[name] || strings.HasPrefix(name, "%") {
+		return ""
+	}
+	return name
+}
+
+// processMatches reports whether the process belongs to an app run as
+// execName or with the given window class. comm is cut to 15 characters by
+// the kernel, so it is compared against the equally cut name.
+func processMatches(p process, execName, wmClass string) bool {
+	for _, name := range p.names {
+		if name == "" {
+			continue
+		}
+		if execName != "" && (name == execName || len(name) == 15 && strings.HasPrefix(execName, name)) {
+			return true
+		}
+		if wmClass != "" && name == wmClass {
+			return true
+		}
+	}
+	return false
+}
+
+// RunningApps returns the PIDs of the running instances of each app: the
+// processes rilaunch started plus, on Linux, processes found in /proc.
+func (am *AppManager) RunningApps() map[string][]int {
+	am.procMu.Lock()
+	defer am.procMu.Unlock()
+
+	if am.running != nil && time.Since(am.runningAt) < runningCacheTTL {
+		return am.running
+	}
+
+	running := launched.snapshot()
+	procs := listProcesses()
+	if len(procs) > 0 {
+		for _, app := range am.GetApps() {
+			// Custom entries often open URLs or files, which name no process.
+			if strings.HasPrefix(app.ID, customIDPrefix) {
+				continue
+			}
+			name, class := execName(app), strings.ToLower(app.WMClass)
+			if name == "" && class == "" {
+				continue
+			}
+			for _, p := range procs {
+				if processMatches(p, name, class) && !containsInt(running[app.ID], p.pid) {
+					running[app.ID] = append(running[app.ID], p.pid)
+				}
+			}
+		}
+	}
+
+	am.running, am.runningAt = running, time.Now()
+	return running
+}
+
+func containsInt(list []int, v int) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
+
+// FocusApp raises a window of a running instance of the app. It fails when
+// no instance is running or windows cannot be focused on this platform, in
+// which case the caller launches the app instead.
+func (am *AppManager) FocusApp(appID string) error {
+	app, ok := am.GetApp(appID)
+	if !ok {
+		return errAppNotRunning
+	}
+	pids := am.RunningApps()[appID]
+	if len(pids) == 0 {
+		return errAppNotRunning
+	}
+
+	classes := []string{strings.ToLower(app.WMClass), execName(app)}
+	return x11.FocusWindow(pids, classes)
+}
@@ -1,75 +1,276 @@
 package appm
 
 import (
+	"path/filepath"
+	"rilaunch/pkg/fuzzy"
 	"strings"
+	"sync"
 	"time"
 )
 
 type AppInfo struct {
-	ID          string    `json:"id"`
-	Name        string    `json:"name"`
-	DisplayName string    `json:"displayName"`
-	Description string    `json:"description"`
-	Icon        string    `json:"icon"`
-	Path        string    `json:"path"`
-	Category    string    `json:"category"`
-	Keywords    []string  `json:"keywords"`
-	LastUsed    time.Time `json:"lastUsed"`
+	ID          string      `json:"id"`
+	Name        string      `json:"name"`
+	DisplayName string      `json:"displayName"`
+	Description string      `json:"description"`
+	Icon        string      `json:"icon"`
+	Path        string      `json:"path"`
+	Category    string      `json:"category"`
+	Keywords    []string    `json:"keywords"`
+	LastUsed    time.Time   `json:"lastUsed"`
+	Actions     []AppAction `json:"actions,omitempty"`
+	WorkDir     string      `json:"workDir,omitempty"`
+	Terminal    bool        `json:"terminal,omitempty"`
+	Source      string      `json:"source,omitempty"`
+	BundleID    string      `json:"bundleId,omitempty"`
+	Version     string      `json:"version,omitempty"`
+	Aliases     []string    `json:"aliases,omitempty"`
+	WMClass     string      `json:"wmClass,omitempty"`
+	MimeTypes   []string    `json:"mimeTypes,omitempty"`
 }
 
+// AppAction is an additional way to start an app, such as a desktop entry's
+// "New Private Window" action.
+type AppAction struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Icon string `json:"icon"`
+	Path string `json:"path"`
+}
+
+// AppManager holds the discovered apps. It is safe for concurrent use;
+// readers get copies, so results can be sorted and kept without locking.
 type AppManager struct {
+	mu   sync.RWMutex
 	apps []AppInfo
+	// index maps app IDs to their position in apps.
+	index map[string]int
+	// shadowed holds apps hidden by an app with the same ID from a source
+	// of higher precedence. They are listed again if that source goes away.
+	shadowed []AppInfo
+	// dirs are the application directories in precedence order, read once
+	// per discovery pass by DiscoverApps; see preferApp.
+	dirs []string
+	// aliases maps user-defined aliases to app IDs or names, and custom
+	// holds the custom entries by app ID; see SetCustomConfig.
+	aliases map[string]string
+	custom  map[string]CustomEntry
+
+	// running caches RunningApps for runningCacheTTL.
+	procMu    sync.Mutex
+	running   map[string][]int
+	runningAt time.Time
 }
 
 func NewAppManager() *AppManager {
 	return &AppManager{
-		apps: make([]AppInfo, 0),
+		apps:  make([]AppInfo, 0),
+		index: make(map[string]int),
 	}
 }
 
 func (am *AppManager) GetApps() []AppInfo {
-	return am.apps
+	am.mu.RLock()
+	defer am.mu.RUnlock()
+	return append([]AppInfo(nil), am.apps...)
+}
+
+// GetApp returns the app with the given ID.
+func (am *AppManager) GetApp(appID string) (AppInfo, bool) {
+	am.mu.RLock()
+	defer am.mu.RUnlock()
+	i, ok := am.index[appID]
+	if !ok {
+		return AppInfo{}, false
+	}
+	return am.apps[i], true
 }
 
+func (am *AppManager) Count() int {
+	am.mu.RLock()
+	defer am.mu.RUnlock()
+	return len(am.apps)
+}
+
+// AddApp adds app to the index. When an app with the same ID is already
+// listed, the one from the source of higher precedence is kept, so the result
+// does not depend on the order apps are discovered in.
 func (am *AppManager) AddApp(app AppInfo) {
-	am.apps = append(am.apps, app)
+	am.mu.Lock()
+	defer am.mu.Unlock()
+	am.addLocked(app)
 }
 
-func (am *AppManager) SearchApps(query string) []AppInfo {
-	if query == "" {
-		return am.apps
+func (am *AppManager) addLocked(app AppInfo) {
+	app.Aliases = am.aliasesForLocked(app)
+	i, ok := am.index[app.ID]
+	if !ok {
+		am.index[app.ID] = len(am.apps)
+		am.apps = append(am.apps, app)
+		return
+	}
+	if preferApp(app, am.apps[i], am.dirs) {
+		am.shadowed = append(am.shadowed, am.apps[i])
+		am.apps[i] = app
+		return
 	}
+	am.shadowed = append(am.shadowed, app)
+}
 
-	var results []AppInfo
-	query = strings.ToLower(query)
+// removeApps drops the listed and shadowed apps for which drop returns true,
+// then lists shadowed apps whose ID is no longer taken.
+func (am *AppManager) removeApps(drop func(AppInfo) bool) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+	am.removeAppsLocked(drop)
+}
 
+func (am *AppManager) removeAppsLocked(drop func(AppInfo) bool) {
+	apps := am.apps[:0]
 	for _, app := range am.apps {
-		if am.matchesQuery(app, query) {
-			results = append(results, app)
+		if !drop(app) {
+			apps = append(apps, app)
 		}
 	}
+	am.apps = apps
+	am.reindexLocked()
 
-	return results
+	var promote []AppInfo
+	shadowed := am.shadowed[:0]
+	for _, app := range am.shadowed {
+		switch {
+		case drop(app):
+		case am.hasLocked(app.ID):
+			shadowed = append(shadowed, app)
+		default:
+			promote = append(promote, app)
+		}
+	}
+	am.shadowed = shadowed
+	for _, app := range promote {
+		am.addLocked(app)
+	}
 }
 
-func (am *AppManager) matchesQuery(app AppInfo, query string) bool {
-	if strings.Contains(strings.ToLower(app.Name), query) {
-		return true
+func (am *AppManager) hasLocked(appID string) bool {
+	_, ok := am.index[appID]
+	return ok
+}
+
+func (am *AppManager) reindexLocked() {
+	am.index = make(map[string]int, len(am.apps))
+	for i, app := range am.apps {
+		am.index[app.ID] = i
 	}
+}
 
-	if strings.Contains(strings.ToLower(app.DisplayName), query) {
-		return true
+// setLastUsed updates the LastUsed time of the apps with IDs in times.
+func (am *AppManager) setLastUsed(times map[string]time.Time) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+	for i, app := range am.apps {
+		if t, ok := times[app.ID]; ok {
+			am.apps[i].LastUsed = t
+		}
+	}
+	for i, app := range am.shadowed {
+		if t, ok := times[app.ID]; ok {
+			am.shadowed[i].LastUsed = t
+		}
 	}
+}
 
-	if strings.Contains(strings.ToLower(app.Description), query) {
-		return true
+// preferApp reports whether a should be listed instead of b, which has the
+// same ID: the app from the earlier of dirs wins, then the one with the
+// lexically smaller source path.
+func preferApp(a, b AppInfo, dirs []string) bool {
+	ra, rb := sourceRank(a.Source, dirs), sourceRank(b.Source, dirs)
+	if ra != rb {
+		return ra < rb
 	}
+	return a.Source < b.Source
+}
 
-	for _, keyword := range app.Keywords {
-		if strings.Contains(strings.ToLower(keyword), query) {
-			return true
+// sourceRank returns the position of the directory in dirs containing
+// source, or the number of directories for apps outside all of them.
+func sourceRank(source string, dirs []string) int {
+	for i, dir := range dirs {
+		if source == dir || strings.HasPrefix(source, dir+string(filepath.Separator)) {
+			return i
+		}
+	}
+	return len(dirs)
+}
+
+// AppMatch is a search result: the app, its fuzzy match score and the
+// matched character ranges of its display name and of matching actions.
+// Args holds the arguments typed after the app, see SplitLaunchQuery, and
+// Running whether an instance of the app is running.
+type AppMatch struct {
+	AppInfo
+	Score         int                      `json:"score"`
+	Matches       []fuzzy.Range            `json:"matches,omitempty"`
+	ActionMatches map[string][]fuzzy.Range `json:"actionMatches,omitempty"`
+	Args          []string                 `json:"args,omitempty"`
+	Running       bool                     `json:"running,omitempty"`
+}
+
+func (am *AppManager) SearchApps(query string) []AppMatch {
+	am.mu.RLock()
+	defer am.mu.RUnlock()
+
+	results := make([]AppMatch, 0)
+	for _, app := range am.apps {
+		if match, ok := am.matchApp(app, query); ok {
+			results = append(results, match)
+		}
+	}
+
+	return results
+}
+
+// matchApp scores query against the app's names, aliases, keywords,
+// description and actions. Keywords and actions count slightly less than
+// the name, and the description only half.
+func (am *AppManager) matchApp(app AppInfo, query string) (AppMatch, bool) {
+	result := AppMatch{AppInfo: app}
+	if strings.TrimSpace(query) == "" {
+		return result, true
+	}
+
+	found := false
+	consider := func(score int) {
+		if !found || score > result.Score {
+			result.Score = score
+		}
+		found = true
+	}
+
+	if m, ok := fuzzy.Score(query, app.DisplayName); ok {
+		consider(m.Score)
+		result.Matches = m.Ranges
+	}
+	if m, ok := fuzzy.Score(query, app.Name); ok {
+		consider(m.Score)
+	}
+	if m, _, ok := fuzzy.Best(query, app.Aliases...); ok {
+		consider(m.Score)
+	}
+	if m, _, ok := fuzzy.Best(query, app.Keywords...); ok {
+		consider(m.Score * 3 / 4)
+	}
+	if m, ok := fuzzy.Score(query, app.Description); ok {
+		consider(m.Score / 2)
+	}
+	for _, action := range app.Actions {
+		if m, ok := fuzzy.Score(query, action.Name); ok {
+			consider(m.Score * 3 / 4)
+			if result.ActionMatches == nil {
+				result.ActionMatches = make(map[string][]fuzzy.Range)
+			}
+			result.ActionMatches[action.ID] = m.Ranges
 		}
 	}
 
-	return false
+	return result, found
 }
@@ -0,0 +1,113 @@
+package appm
+
+import (
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// setAppDirs points the application directories at temporary ones and
+// returns the user's data home and the first system data dir.
+func setAppDirs(t *testing.T) (dataHome, dataDir string) {
+	t.Helper()
+	if runtime.GOOS != "linux" {
+		t.Skip("application directories come from the XDG environment on Linux only")
+	}
+	home := t.TempDir()
+	dataHome = filepath.Join(home, ".local", "share")
+	dataDir = filepath.Join(home, "usr", "share")
+	t.Setenv("HOME", home)
+	t.Setenv("PAL_CONFIG_DIR", filepath.Join(home, ".config", "pal"))
+	t.Setenv("XDG_DATA_HOME", dataHome)
+	t.Setenv("XDG_DATA_DIRS", dataDir)
+	return dataHome, dataDir
+}
+
+func TestSourceRank(t *testing.T) {
+	dataHome, dataDir := setAppDirs(t)
+	dirs := AppDirs()
+	outside := len(dirs)
+
+	tests := []struct {
+		source string
+		want   int
+	}{
+		{filepath.Join(dataHome, "applications", "gimp.desktop"), 0},
+		{filepath.Join(dataHome, "applications"), 0},
+		{filepath.Join(dataDir, "applications", "gimp.desktop"), 1},
+		{filepath.Join(dataDir, "applications", "kde4", "gimp.desktop"), 1},
+		{filepath.Join(dataDir, "applications-old", "gimp.desktop"), outside},
+		{"/opt/gimp/gimp.desktop", outside},
+		{"", outside},
+	}
+	for _, tt := range tests {
+		if got := sourceRank(tt.source, dirs); got != tt.want {
+			t.Errorf("sourceRank(%q) = %d, want %d", tt.source, got, tt.want)
+		}
+	}
+}
+
+func TestPreferApp(t *testing.T) {
+	dataHome, dataDir := setAppDirs(t)
+	user := filepath.Join(dataHome, "applications")
+	system := filepath.Join(dataDir, "applications")
+	dirs := AppDirs()
+
+	tests := []struct {
+		name string
+		a, b string
+		want bool
+	}{
+		{"user dir over system dir", filepath.Join(user, "gimp.desktop"), filepath.Join(system, "gimp.desktop"), true},
+		{"system dir under user dir", filepath.Join(system, "gimp.desktop"), filepath.Join(user, "gimp.desktop"), false},
+		{"app dir over outside", filepath.Join(system, "gimp.desktop"), "/opt/gimp/gimp.desktop", true},
+		{"outside under app dir", "/opt/gimp/gimp.desktop", filepath.Join(system, "gimp.desktop"), false},
+		{"same dir, smaller path", filepath.Join(system, "a", "gimp.desktop"), filepath.Join(system, "b", "gimp.desktop"), true},
+		{"same dir, larger path", filepath.Join(system, "b", "gimp.desktop"), filepath.Join(system, "a", "gimp.desktop"), false},
+		{"both outside", "/opt/a/gimp.desktop", "/opt/b/gimp.desktop", true},
+		{"same source", filepath.Join(system, "gimp.desktop"), filepath.Join(system, "gimp.desktop"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := AppInfo{ID: "gimp", Source: tt.a}
+			b := AppInfo{ID: "gimp", Source: tt.b}
+			if got := preferApp(a, b, dirs); got != tt.want {
+				t.Errorf("preferApp(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddAppOrder(t *testing.T) {
+	dataHome, dataDir := setAppDirs(t)
+	apps := []AppInfo{
+		{ID: "gimp", Name: "outside", Source: "/opt/gimp/gimp.desktop"},
+		{ID: "gimp", Name: "system", Source: filepath.Join(dataDir, "applications", "gimp.desktop")},
+		{ID: "gimp", Name: "user", Source: filepath.Join(dataHome, "applications", "gimp.desktop")},
+	}
+	orders := [][]int{{0, 1, 2}, {2, 1, 0}, {1, 2, 0}, {0, 2, 1}}
+
+	for _, order := range orders {
+		am := NewAppManager()
+		am.dirs = AppDirs()
+		for _, i := range order {
+			am.AddApp(apps[i])
+		}
+		if got, _ := am.GetApp("gimp"); got.Name != "user" {
+			t.Errorf("order %v: listed %q, want user", order, got.Name)
+		}
+		if n := am.Count(); n != 1 {
+			t.Errorf("order %v: %d apps listed, want 1", order, n)
+		}
+
+		// Removing the listed app brings back the next one in line.
+		am.removeApps(func(app AppInfo) bool { return app.Name == "user" })
+		if got, _ := am.GetApp("gimp"); got.Name != "system" {
+			t.Errorf("order %v: after removal listed %q, want system", order, got.Name)
+		}
+		am.removeApps(func(app AppInfo) bool { return app.Name == "system" })
+		if got, _ := am.GetApp("gimp"); got.Name != "outside" {
+			t.Errorf("order %v: after removals listed %q, want outside", order, got.Name)
+		}
+	}
+}
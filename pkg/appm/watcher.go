@@ -0,0 +1,331 @@
+package appm
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"rilaunch/pkg/config"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// watchDebounce batches the bursts of events a package install produces.
+	watchDebounce = 500 * time.Millisecond
+	// watchPollInterval is how often the polling watcher rescans, and how
+	// often missing directories are checked for by the inotify watcher.
+	watchPollInterval = 5 * time.Second
+)
+
+// rescanAll is reported by a dirWatcher in place of a path when it lost
+// track of changes, so everything has to be rescanned.
+const rescanAll = ""
+
+// dirWatcher reports paths below the watched directories, and watched files,
+// that were created, modified or removed.
+type dirWatcher interface {
+	Events() <-chan string
+	Close() error
+}
+
+// Watch keeps the app index in sync with the application directories until
+// ctx is cancelled, calling onChange after each batch of updates. Edits of
+// the custom entries file reload the custom entries, and edits of the
+// settings, which choose some of the directories scanned, rescan all apps.
+func (m *Manager) Watch(ctx context.Context, onChange func()) {
+	customPath := CustomConfigPath()
+	settingsPath := config.SettingsFilePath()
+	files := []string{customPath, settingsPath}
+	dirs := AppDirs()
+
+	w := newWatcher(dirs, files)
+	defer func() { w.Close() }()
+
+	pending := make(map[string]bool)
+	reloadCustom, rescan := false, false
+	var debounce <-chan time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case path, ok := <-w.Events():
+			if !ok {
+				return
+			}
+			switch path {
+			case customPath:
+				reloadCustom = true
+			case settingsPath, rescanAll:
+				rescan = true
+			default:
+				pending[path] = true
+			}
+			if debounce == nil {
+				debounce = time.After(watchDebounce)
+			}
+		case <-debounce:
+			debounce = nil
+			if rescan {
+				if err := m.Refresh(); err != nil {
+					fmt.Printf("Warning: %v\n", err)
+				}
+				// The settings may have changed the directories to watch.
+				if next := AppDirs(); !slices.Equal(next, dirs) {
+					w.Close()
+					dirs = next
+					w = newWatcher(dirs, files)
+				}
+			} else {
+				apps := m.apps()
+				for path := range pending {
+					apps.refreshPath(path)
+				}
+				if reloadCustom {
+					loadCustomEntries(apps)
+				}
+				fmt.Printf("Refreshed %d changed application paths\n", len(pending))
+				m.applyLaunchHistory(apps)
+			}
+			pending = make(map[string]bool)
+			reloadCustom, rescan = false, false
+			if onChange != nil {
+				onChange()
+			}
+		}
+	}
+}
+
+// newWatcher watches dirs and files with inotify where it is available, and
+// by polling elsewhere.
+func newWatcher(dirs, files []string) dirWatcher {
+	w, err := newInotifyWatcher(dirs, files)
+	if err != nil {
+		fmt.Printf("Warning: falling back to polling for app changes: %v\n", err)
+		return newPollWatcher(dirs, files, watchPollInterval)
+	}
+	return w
+}
+
+// refreshPath re-reads the apps discovered from path, adding, updating or
+// removing them. Directories are rescanned as a whole.
+func (am *AppManager) refreshPath(path string) {
+	if runtime.GOOS == "linux" && am.refreshDesktopPath(path) {
+		return
+	}
+
+	am.removeSource(path)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+
+	if !info.IsDir() || isAppBundle(path) {
+		if app := am.parseAppPath(path); app != nil {
+			am.AddApp(*app)
+		}
+		return
+	}
+
+	filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || p == path {
+			return nil
+		}
+		if d.IsDir() && !isAppBundle(p) {
+			return nil
+		}
+		if app := am.parseAppPath(p); app != nil {
+			am.AddApp(*app)
+		}
+		if d.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+}
+
+// refreshDesktopPath handles changes to desktop files by re-resolving their
+// IDs across all applications directories. It reports false for paths that
+// are not below an applications directory.
+func (am *AppManager) refreshDesktopPath(path string) bool {
+	inAppDir := false
+	for _, dir := range desktopAppDirs() {
+		if path == dir || strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			inAppDir = true
+			break
+		}
+	}
+	if !inAppDir {
+		return false
+	}
+
+	ids := make(map[string]bool)
+	prefix := path + string(filepath.Separator)
+	for _, app := range am.GetApps() {
+		if app.Source == path || strings.HasPrefix(app.Source, prefix) {
+			ids[app.ID] = true
+		}
+	}
+	if strings.HasSuffix(path, ".desktop") {
+		ids[desktopFileID(path)] = true
+	}
+	filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && strings.HasSuffix(p, ".desktop") {
+			ids[desktopFileID(p)] = true
+		}
+		return nil
+	})
+
+	for id := range ids {
+		am.refreshDesktopID(id)
+	}
+	return true
+}
+
+// parseAppPath parses a single application file or bundle for the current
+// OS, returning nil if the path is not a listable app.
+func (am *AppManager) parseAppPath(path string) *AppInfo {
+	var app *AppInfo
+	switch runtime.GOOS {
+	case "linux":
+		if isAppImage(path) {
+			app = parseAppImage(path)
+			break
+		}
+		if !strings.HasSuffix(path, ".desktop") {
+			return nil
+		}
+		parsed, err := am.parseDesktopFile(path)
+		if err != nil || parsed.Path == "" {
+			return nil
+		}
+		app = parsed
+	case "darwin":
+		if !isAppBundle(path) {
+			return nil
+		}
+		app = am.parseMacOSApp(path)
+	case "windows":
+		ext := strings.ToLower(filepath.Ext(path))
+		if ext != ".exe" && ext != ".lnk" {
+			return nil
+		}
+		app = am.parseWindowsApp(path)
+	}
+	if app == nil || app.Name == "" {
+		return nil
+	}
+	return app
+}
+
+// removeSource drops the apps discovered from path or from anywhere below it.
+func (am *AppManager) removeSource(path string) {
+	prefix := path + string(filepath.Separator)
+	am.removeApps(func(app AppInfo) bool {
+		return app.Source == path || strings.HasPrefix(app.Source, prefix)
+	})
+}
+
+func isAppBundle(path string) bool {
+	return runtime.GOOS == "darwin" && strings.HasSuffix(path, ".app")
+}
+
+// pollWatcher detects changes by periodically comparing modification times.
+type pollWatcher struct {
+	events chan string
+	done   chan struct{}
+}
+
+func newPollWatcher(dirs, files []string, interval time.Duration) *pollWatcher {
+	w := &pollWatcher{
+		events: make(chan string, 64),
+		done:   make(chan struct{}),
+	}
+	go w.run(dirs, files, interval)
+	return w
+}
+
+func (w *pollWatcher) Events() <-chan string {
+	return w.events
+}
+
+func (w *pollWatcher) Close() error {
+	close(w.done)
+	return nil
+}
+
+func (w *pollWatcher) run(dirs, files []string, interval time.Duration) {
+	defer close(w.events)
+
+	prev := snapshotDirs(dirs, files)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+		}
+
+		next := snapshotDirs(dirs, files)
+		for path, mtime := range next {
+			if old, ok := prev[path]; !ok || !old.Equal(mtime) {
+				if !w.send(path) {
+					return
+				}
+			}
+		}
+		for path := range prev {
+			if _, ok := next[path]; !ok {
+				if !w.send(path) {
+					return
+				}
+			}
+		}
+		prev = next
+	}
+}
+
+func (w *pollWatcher) send(path string) bool {
+	select {
+	case w.events <- path:
+		return true
+	case <-w.done:
+		return false
+	}
+}
+
+// snapshotDirs records the modification time of every file below dirs, and
+// of files, treating app bundles as single entries.
+func snapshotDirs(dirs, files []string) map[string]time.Time {
+	snapshot := make(map[string]time.Time)
+	for _, file := range files {
+		if info, err := os.Stat(file); err == nil {
+			snapshot[file] = info.ModTime()
+		}
+	}
+	for _, dir := range dirs {
+		filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || path == dir {
+				return nil
+			}
+			if d.IsDir() && !isAppBundle(path) {
+				return nil
+			}
+			if info, err := d.Info(); err == nil {
+				snapshot[path] = info.ModTime()
+			}
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		})
+	}
+	return snapshot
+}
@@ -0,0 +1,259 @@
+package appm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const inotifyMask = syscall.IN_CREATE | syscall.IN_CLOSE_WRITE | syscall.IN_MODIFY |
+	syscall.IN_ATTRIB | syscall.IN_DELETE | syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO |
+	syscall.IN_DELETE_SELF | syscall.IN_MOVE_SELF
+
+// inotifyFileMask watches the directories of single files. It leaves out
+// IN_MODIFY, so writes to other files there, such as the database, do not
+// wake the watcher; files are only seen once written and closed, or
+// replaced.
+const inotifyFileMask = syscall.IN_CLOSE_WRITE | syscall.IN_DELETE | syscall.IN_MOVED_FROM |
+	syscall.IN_MOVED_TO | syscall.IN_DELETE_SELF | syscall.IN_MOVE_SELF
+
+// inotifyWatcher watches the directories, and their subdirectories, with
+// inotify, as well as single files. Watched directories that do not exist,
+// or are removed, are checked for periodically.
+type inotifyWatcher struct {
+	file   *os.File
+	fd     int
+	events chan string
+	// done is closed by Close, or when reading fails; events is closed once
+	// both goroutines sending on it have returned.
+	done     chan struct{}
+	doneOnce sync.Once
+	senders  sync.WaitGroup
+
+	// files are the watched files, and fileDirs their directories, which
+	// are watched on their own rather than recursively.
+	files    map[string]bool
+	fileDirs map[string]bool
+	// roots are the directories passed in. Only these are put back in
+	// missing when removed; their subdirectories are watched again as
+	// they are created.
+	roots map[string]bool
+
+	mu      sync.Mutex
+	watches map[int32]string
+	missing map[string]bool
+}
+
+func newInotifyWatcher(dirs, files []string) (dirWatcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &inotifyWatcher{
+		// A non-blocking fd goes through the runtime poller, so Close
+		// unblocks a pending Read.
+		file:     os.NewFile(uintptr(fd), "inotify"),
+		fd:       fd,
+		events:   make(chan string, 64),
+		done:     make(chan struct{}),
+		files:    make(map[string]bool),
+		fileDirs: make(map[string]bool),
+		roots:    make(map[string]bool),
+		watches:  make(map[int32]string),
+		missing:  make(map[string]bool),
+	}
+	for _, file := range files {
+		w.files[file] = true
+		w.fileDirs[filepath.Dir(file)] = true
+		w.roots[filepath.Dir(file)] = true
+	}
+	for _, dir := range dirs {
+		w.roots[dir] = true
+	}
+	for dir := range w.roots {
+		if _, err := os.Stat(dir); err != nil {
+			w.missing[dir] = true
+			continue
+		}
+		w.addRoot(dir)
+	}
+
+	w.senders.Add(2)
+	go w.readEvents()
+	go w.checkMissing()
+	go func() {
+		w.senders.Wait()
+		close(w.events)
+	}()
+	return w, nil
+}
+
+func (w *inotifyWatcher) Events() <-chan string {
+	return w.events
+}
+
+func (w *inotifyWatcher) Close() error {
+	w.stop()
+	return w.file.Close()
+}
+
+func (w *inotifyWatcher) stop() {
+	w.doneOnce.Do(func() { close(w.done) })
+}
+
+// addRoot watches one of the directories passed in: recursively, or on its
+// own if it holds watched files.
+func (w *inotifyWatcher) addRoot(dir string) {
+	if w.fileDirs[dir] {
+		w.addWatch(dir, inotifyFileMask)
+		return
+	}
+	w.addRecursive(dir)
+}
+
+func (w *inotifyWatcher) addRecursive(dir string) {
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		w.addWatch(path, inotifyMask)
+		return nil
+	})
+}
+
+func (w *inotifyWatcher) addWatch(path string, mask uint32) {
+	wd, err := syscall.InotifyAddWatch(w.fd, path, mask)
+	if err != nil {
+		return
+	}
+	w.mu.Lock()
+	w.watches[int32(wd)] = path
+	w.mu.Unlock()
+}
+
+func (w *inotifyWatcher) readEvents() {
+	defer w.senders.Done()
+	// A read error ends the watch, so checkMissing stops as well.
+	defer w.stop()
+
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		n, err := w.file.Read(buf)
+		if err != nil {
+			return
+		}
+
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			var event syscall.InotifyEvent
+			binary.Read(bytes.NewReader(buf[offset:offset+syscall.SizeofInotifyEvent]), binary.NativeEndian, &event)
+			nameStart := offset + syscall.SizeofInotifyEvent
+			name := string(bytes.TrimRight(buf[nameStart:nameStart+int(event.Len)], "\x00"))
+			offset = nameStart + int(event.Len)
+
+			if event.Mask&syscall.IN_Q_OVERFLOW != 0 {
+				// Events were dropped, so any path may have changed.
+				if !w.send(rescanAll) {
+					return
+				}
+				continue
+			}
+
+			w.mu.Lock()
+			dir, ok := w.watches[event.Wd]
+			if event.Mask&syscall.IN_IGNORED != 0 {
+				delete(w.watches, event.Wd)
+			}
+			w.mu.Unlock()
+			if !ok {
+				continue
+			}
+
+			path := dir
+			if name != "" {
+				path = filepath.Join(dir, name)
+			}
+			if event.Mask&(syscall.IN_DELETE_SELF|syscall.IN_MOVE_SELF) != 0 && w.roots[dir] {
+				w.mu.Lock()
+				w.missing[dir] = true
+				w.mu.Unlock()
+			}
+			if w.fileDirs[dir] {
+				if !w.files[path] {
+					continue
+				}
+			} else if event.Mask&syscall.IN_ISDIR != 0 && event.Mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0 {
+				w.addRecursive(path)
+			}
+
+			if !w.send(path) {
+				return
+			}
+		}
+	}
+}
+
+func (w *inotifyWatcher) send(path string) bool {
+	select {
+	case w.events <- path:
+		return true
+	case <-w.done:
+		return false
+	}
+}
+
+// checkMissing starts watching directories that appear after startup, such
+// as ~/.local/share/applications on a fresh account, or that were removed
+// and created again.
+func (w *inotifyWatcher) checkMissing() {
+	defer w.senders.Done()
+	ticker := time.NewTicker(watchPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+		}
+
+		var found []string
+		w.mu.Lock()
+		for dir := range w.missing {
+			if _, err := os.Stat(dir); err == nil {
+				delete(w.missing, dir)
+				found = append(found, dir)
+			}
+		}
+		w.mu.Unlock()
+
+		for _, dir := range found {
+			w.addRoot(dir)
+			for _, path := range w.changedPaths(dir) {
+				if !w.send(path) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// changedPaths returns the paths to report when dir appears: the watched
+// files in it, or dir itself.
+func (w *inotifyWatcher) changedPaths(dir string) []string {
+	if !w.fileDirs[dir] {
+		return []string{dir}
+	}
+	var paths []string
+	for file := range w.files {
+		if filepath.Dir(file) == dir {
+			paths = append(paths, file)
+		}
+	}
+	return paths
+}
@@ -0,0 +1,142 @@
+package appm
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestInotifyWatcherClose(t *testing.T) {
+	dir := t.TempDir()
+	missing := filepath.Join(dir, "later")
+	for i := 0; i < 20; i++ {
+		w, err := newInotifyWatcher([]string{dir, missing}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i%2 == 0 {
+			os.Mkdir(missing, 0o755)
+		} else {
+			os.Remove(missing)
+		}
+		w.Close()
+
+		// Events is closed once both senders have stopped.
+		timeout := time.After(5 * time.Second)
+		for open := true; open; {
+			select {
+			case _, open = <-w.Events():
+			case <-timeout:
+				t.Fatal("events channel not closed after Close")
+			}
+		}
+	}
+}
+
+// nextEvent returns the next reported path, or "" after a timeout.
+func nextEvent(t *testing.T, w dirWatcher, timeout time.Duration) (string, bool) {
+	t.Helper()
+	select {
+	case path := <-w.Events():
+		return path, true
+	case <-time.After(timeout):
+		return "", false
+	}
+}
+
+func TestInotifyWatcherFiles(t *testing.T) {
+	configDir := t.TempDir()
+	entries := filepath.Join(configDir, "entries.json")
+	settings := filepath.Join(configDir, "settings.json")
+	w, err := newInotifyWatcher(nil, []string{entries, settings})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	// Writes to other files in the directory are not reported.
+	db := filepath.Join(configDir, "palcb.db")
+	f, err := os.Create(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("data"))
+	f.Close()
+	if err := os.WriteFile(entries+".tmp", []byte("{}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(entries+".tmp", entries); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(settings, []byte("{}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{entries, settings} {
+		path, ok := nextEvent(t, w, 5*time.Second)
+		if !ok {
+			t.Fatalf("no event for %s", want)
+		}
+		if path != want {
+			t.Errorf("event for %s, want %s", path, want)
+		}
+	}
+	if path, ok := nextEvent(t, w, 200*time.Millisecond); ok {
+		t.Errorf("unexpected event for %s", path)
+	}
+}
+
+func TestInotifyWatcherMissing(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "applications")
+	sub := filepath.Join(root, "kde")
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	dw, err := newInotifyWatcher([]string{root}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dw.Close()
+	w := dw.(*inotifyWatcher)
+
+	missing := func() map[string]bool {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		return maps.Clone(w.missing)
+	}
+	drain := func() {
+		for {
+			if _, ok := nextEvent(t, w, 200*time.Millisecond); !ok {
+				return
+			}
+		}
+	}
+
+	// A removed subdirectory is not checked for again; it is watched
+	// anew when its parent reports it created.
+	if err := os.Remove(sub); err != nil {
+		t.Fatal(err)
+	}
+	drain()
+	if m := missing(); len(m) != 0 {
+		t.Errorf("missing after removing a subdirectory = %v, want none", m)
+	}
+
+	// A removed root is checked for, once however often it goes.
+	for i := 0; i < 3; i++ {
+		if err := os.Remove(root); err != nil {
+			t.Fatal(err)
+		}
+		drain()
+		if err := os.Mkdir(root, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.Remove(root)
+	drain()
+	if m := missing(); len(m) != 1 || !m[root] {
+		t.Errorf("missing after removing the root = %v, want only %s", m, root)
+	}
+}
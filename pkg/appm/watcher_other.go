@@ -0,0 +1,9 @@
+//go:build !linux
+
+package appm
+
+import "fmt"
+
+func newInotifyWatcher(dirs, files []string) (dirWatcher, error) {
+	return nil, fmt.Errorf("inotify is only available on linux")
+}
@@ -0,0 +1,204 @@
+package appm
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"rilaunch/pkg/config"
+	"strings"
+)
+
+// xdgDataDirs returns the XDG data directories in precedence order: the
+// user's data home first, then $XDG_DATA_DIRS, then the Flatpak and Snap
+// export locations when the session did not already list them.
+func xdgDataDirs() []string {
+	home := os.Getenv("HOME")
+
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	dirs := []string{dataHome}
+
+	dataDirs := os.Getenv("XDG_DATA_DIRS")
+	if dataDirs == "" {
+		dataDirs = "/usr/local/share:/usr/share"
+	}
+	dirs = append(dirs, filepath.SplitList(dataDirs)...)
+
+	dirs = append(dirs,
+		filepath.Join(dataHome, "flatpak", "exports", "share"),
+		"/var/lib/flatpak/exports/share",
+		"/var/lib/snapd/desktop",
+	)
+
+	seen := make(map[string]bool)
+	var unique []string
+	for _, dir := range dirs {
+		if dir == "" || !filepath.IsAbs(dir) {
+			continue
+		}
+		dir = filepath.Clean(dir)
+		if !seen[dir] {
+			seen[dir] = true
+			unique = append(unique, dir)
+		}
+	}
+	return unique
+}
+
+// desktopAppDirs returns the applications directories, highest precedence first.
+func desktopAppDirs() []string {
+	var dirs []string
+	for _, dir := range xdgDataDirs() {
+		dirs = append(dirs, filepath.Join(dir, "applications"))
+	}
+	return dirs
+}
+
+// appImageDirs returns the user-configured folders scanned for AppImages.
+func appImageDirs() []string {
+	var dirs []string
+	for _, dir := range config.LoadSettings().AppImageDirs {
+		if dir = config.ExpandPath(dir); dir != "" {
+			dirs = append(dirs, filepath.Clean(dir))
+		}
+	}
+	return dirs
+}
+
+// desktopFileID returns the desktop file ID of path: its path relative to the
+// applications directory containing it, with "/" replaced by "-".
+func desktopFileID(path string) string {
+	for _, dir := range desktopAppDirs() {
+		if rel, err := filepath.Rel(dir, path); err == nil && !strings.HasPrefix(rel, "..") {
+			return strings.ReplaceAll(filepath.ToSlash(rel), "/", "-")
+		}
+	}
+	return filepath.Base(path)
+}
+
+// desktopIDCandidates returns the paths below dir that could hold the desktop
+// file ID, trying subdirectory prefixes such as "kde4-foo" → "kde4/foo".
+func desktopIDCandidates(dir, id string) []string {
+	candidates := []string{filepath.Join(dir, id)}
+	rel := id
+	for i := strings.IndexByte(rel, '-'); i >= 0; i = strings.IndexByte(rel, '-') {
+		rel = rel[:i] + "/" + rel[i+1:]
+		candidates = append(candidates, filepath.Join(dir, filepath.FromSlash(rel)))
+	}
+	return candidates
+}
+
+// resolveDesktopID returns the highest precedence desktop file for the ID,
+// or "" if no applications directory has one.
+func resolveDesktopID(id string) string {
+	for _, dir := range desktopAppDirs() {
+		for _, candidate := range desktopIDCandidates(dir, id) {
+			if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+				return candidate
+			}
+		}
+	}
+	return ""
+}
+
+// refreshDesktopID re-resolves a desktop file ID after one of its files
+// changed, so a user entry replaces, or a removed one uncovers, the system
+// entry with the same ID.
+func (am *AppManager) refreshDesktopID(id string) {
+	am.removeApps(func(app AppInfo) bool { return app.ID == id })
+
+	path := resolveDesktopID(id)
+	if path == "" {
+		return
+	}
+	if app, err := am.parseDesktopFile(path); err == nil && app.Name != "" && app.Path != "" {
+		am.AddApp(*app)
+	}
+}
+
+func (am *AppManager) scanAppImages(dir string) error {
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if app := parseAppImage(filepath.Join(dir, entry.Name())); app != nil {
+			am.AddApp(*app)
+		}
+	}
+	return nil
+}
+
+func isAppImage(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".appimage")
+}
+
+// parseAppImage describes an executable AppImage. The name is the file name
+// up to its version or architecture suffix, e.g. "Obsidian-1.5.3.AppImage".
+func parseAppImage(path string) *AppInfo {
+	if !isAppImage(path) {
+		return nil
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() || info.Mode()&0o111 == 0 {
+		return nil
+	}
+
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	var nameParts []string
+	for _, part := range strings.FieldsFunc(base, func(r rune) bool { return r == '-' || r == '_' }) {
+		if isVersionOrArch(part) {
+			break
+		}
+		nameParts = append(nameParts, part)
+	}
+	name := strings.Join(nameParts, " ")
+	if name == "" {
+		name = base
+	}
+
+	return &AppInfo{
+		ID:          "appimage:" + filepath.Base(path),
+		Name:        name,
+		DisplayName: name,
+		Description: fmt.Sprintf("AppImage: %s", filepath.Base(path)),
+		Path:        quoteExecArg(path),
+		Category:    "Application",
+		Source:      path,
+	}
+}
+
+func isVersionOrArch(part string) bool {
+	if part[0] >= '0' && part[0] <= '9' {
+		return true
+	}
+	switch strings.ToLower(part) {
+	case "x86", "amd64", "i386", "i686", "aarch64", "arm64", "armhf":
+		return true
+	}
+	return false
+}
+
+// quoteExecArg quotes an argument for use in an Exec line.
+func quoteExecArg(arg string) string {
+	if !strings.ContainsAny(arg, " \t\n\"'\\><~|&;$*?#()`") {
+		return arg
+	}
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(arg); i++ {
+		if strings.IndexByte("\"`$\\", arg[i]) >= 0 {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(arg[i])
+	}
+	b.WriteByte('"')
+	return b.String()
+}
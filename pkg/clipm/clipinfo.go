@@ -1,9 +1,11 @@
 package clipm
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"rilaunch/pkg/config"
+	"rilaunch/pkg/fuzzy"
 	"sort"
 
 	"github.com/rs/zerolog"
@@ -22,20 +24,31 @@ type ClipInfo struct {
 	Hash        string   `json:"hash"`
 	IsSecret    bool     `json:"is_secret"`
 	Tag         []string `json:"tag"`
+	// Timestamp is when the entry was last copied, FirstSeen when it was
+	// first copied, and CopyCount how often. Entries recorded before copies
+	// were counted have neither.
+	FirstSeen int64 `json:"first_seen,omitempty"`
+	CopyCount int   `json:"copy_count,omitempty"`
+	// Pinned entries are exempt from retention pruning.
+	Pinned bool `json:"pinned"`
+	// Encrypted holds the sealed content of secret entries, whose Content is
+	// empty in the database and SecretMask in listings.
+	Encrypted []byte `json:"encrypted,omitempty"`
+	// SecretReason names what the detectors recognized in entries marked
+	// secret on capture, such as "GitHub token".
+	SecretReason string `json:"secret_reason,omitempty"`
+	// ContentType is ContentTypeText, or ContentTypeImage for images, whose
+	// PNG and thumbnail are kept in their own buckets; Content describes them.
+	ContentType string `json:"content_type"`
+	Width       int    `json:"width,omitempty"`
+	Height      int    `json:"height,omitempty"`
+	Size        int    `json:"size,omitempty"`
 }
 
 func (clipm *ClipM) Create(key string, clipInfo ClipInfo) error {
 	return clipm.DB.Update(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket(config.ClipBucket)
-		if bucket == nil {
-			return fmt.Errorf("clipInfo not found")
-		}
-		data, err := json.Marshal(clipInfo)
-		if err != nil {
-			return err
-		}
 		fmt.Println("Saving to clipdb...")
-		return bucket.Put([]byte(key), data)
+		return putClip(tx, []byte(key), clipInfo)
 	})
 }
 
@@ -72,6 +85,12 @@ func (clipm *ClipM) ReadAll() (*[]ClipInfo, error) {
 				return nil
 			}
 			data.Hash = string(k)
+			if data.ContentType == "" {
+				data.ContentType = ContentTypeText
+			}
+			if data.IsSecret {
+				data.Content, data.Encrypted = SecretMask, nil
+			}
 			clipInfos = append(clipInfos, data)
 			return nil
 		})
@@ -85,62 +104,118 @@ func (clipm *ClipM) ReadAll() (*[]ClipInfo, error) {
 
 func (clipm *ClipM) Update(key string, clipInfo ClipInfo) error {
 	return clipm.DB.Update(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket(config.ClipBucket)
-		if bucket == nil {
-			return fmt.Errorf("clipInfo not found")
-		}
-		data, err := json.Marshal(clipInfo)
-		if err != nil {
-			return err
-		}
-		return bucket.Put([]byte(key), data)
+		return putClip(tx, []byte(key), clipInfo)
 	})
 }
 
-func (clipm *ClipM) MarkSecret(key string) error {
-	return clipm.DB.Update(func(tx *bolt.Tx) error {
+// Recopy records another copy of an existing entry: it moves the entry to
+// the top of the history and counts the copy, keeping its content, flags and
+// tags. It reports whether the entry exists.
+func (clipm *ClipM) Recopy(key string, timestamp int64, application string) (bool, error) {
+	found := false
+	err := clipm.DB.Update(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket(config.ClipBucket)
 		if bucket == nil {
 			return fmt.Errorf("clipInfo not found")
 		}
-		var clipInfo ClipInfo
 		data := bucket.Get([]byte(key))
-		err := json.Unmarshal(data, &clipInfo)
-		if err != nil {
-			return err
+		if data == nil {
+			return nil
 		}
-		clipInfo.IsSecret = !clipInfo.IsSecret
-		data, err = json.Marshal(clipInfo)
-		if err != nil {
+		var clipInfo ClipInfo
+		if err := json.Unmarshal(data, &clipInfo); err != nil {
 			return err
 		}
-		return bucket.Put([]byte(key), data)
+		found = true
+		if clipInfo.FirstSeen == 0 {
+			clipInfo.FirstSeen = clipInfo.Timestamp
+		}
+		clipInfo.CopyCount = max(clipInfo.CopyCount, 1) + 1
+		clipInfo.Timestamp = timestamp
+		if clipInfo.Application == "" {
+			clipInfo.Application = application
+		}
+		return putClip(tx, []byte(key), clipInfo)
 	})
+	return found, err
 }
 
+// DeleteBucket deletes all entries but the pinned ones, with their images.
 func (clipm *ClipM) DeleteBucket() error {
 	return clipm.DB.Update(func(tx *bolt.Tx) error {
-		b := tx.Bucket(config.ClipBucket)
-		if b == nil {
-			return nil
-		}
-		var keys [][]byte
-		c := b.Cursor()
-		for k, _ := c.First(); k != nil; k, _ = c.Next() {
-			// Make a copy of the key because boltDB keys are only valid for the life of the transaction/cursor step
-			kCopy := make([]byte, len(k))
-			copy(kCopy, k)
-			keys = append(keys, kCopy)
+		pinned := make(map[string]bool)
+		if b := tx.Bucket(config.ClipBucket); b != nil {
+			b.ForEach(func(k, v []byte) error {
+				var clipInfo ClipInfo
+				if json.Unmarshal(v, &clipInfo) == nil && clipInfo.Pinned {
+					pinned[string(k)] = true
+				}
+				return nil
+			})
 		}
-		for _, k := range keys {
-			if err := b.Delete(k); err != nil {
-				return err
+		for _, name := range [][]byte{config.ClipBucket, config.ClipImageBucket, config.ClipThumbBucket, config.ClipIndexBucket} {
+			b := tx.Bucket(name)
+			if b == nil {
+				continue
+			}
+			var keys [][]byte
+			c := b.Cursor()
+			for k, _ := c.First(); k != nil; k, _ = c.Next() {
+				entryKey := k
+				if bytes.Equal(name, config.ClipIndexBucket) {
+					entryKey = entryKeyOf(k)
+				}
+				if pinned[string(entryKey)] {
+					continue
+				}
+				// Make a copy of the key because boltDB keys are only valid for the life of the transaction/cursor step
+				kCopy := make([]byte, len(k))
+				copy(kCopy, k)
+				keys = append(keys, kCopy)
+			}
+			for _, k := range keys {
+				if err := b.Delete(k); err != nil {
+					return err
+				}
 			}
 		}
 		return nil
 	})
 }
 
+// Filter returns the entries whose content fuzzy-matches query, best matches
+// first, then pinned and newest first among equal matches. app: and tag:
+// terms in the query, such as "app:firefox tag:work", keep only the entries
+// copied in those apps and with those tags.
+func (clipm *ClipM) Filter(clipInfos []ClipInfo, query string) []ClipInfo {
+	type scored struct {
+		info  ClipInfo
+		score int
+	}
+	q := parseClipQuery(query)
+	var matches []scored
+	for _, info := range clipInfos {
+		if !q.matchesFilters(info) {
+			continue
+		}
+		if m, ok := fuzzy.Score(q.text, info.Content); ok {
+			matches = append(matches, scored{info, m.Score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return listedBefore(matches[i].info, matches[j].info)
+	})
+
+	filtered := make([]ClipInfo, len(matches))
+	for i, m := range matches {
+		filtered[i] = m.info
+	}
+	return filtered
+}
+
 func (clipm *ClipM) Reverse(clipInfos []ClipInfo) {
 	for i, j := 0, len(clipInfos)-1; i < j; i, j = i+1, j-1 {
 		clipInfos[i], clipInfos[j] = clipInfos[j], clipInfos[i]
@@ -155,4 +230,12 @@ type ByTimestamp []ClipInfo
 
 func (a ByTimestamp) Len() int           { return len(a) }
 func (a ByTimestamp) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
-func (a ByTimestamp) Less(i, j int) bool { return a[i].Timestamp > a[j].Timestamp }
+func (a ByTimestamp) Less(i, j int) bool { return listedBefore(a[i], a[j]) }
+
+// listedBefore orders pinned entries first, then newest first.
+func listedBefore(a, b ClipInfo) bool {
+	if a.Pinned != b.Pinned {
+		return a.Pinned
+	}
+	return a.Timestamp > b.Timestamp
+}
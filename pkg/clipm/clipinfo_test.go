@@ -0,0 +1,64 @@
+package clipm
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRecopy(t *testing.T) {
+	cm := newTestClipM(t)
+	now := time.Now()
+	k := addClips(t, cm, 3, now, nil)
+	if err := cm.Pin(k[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := cm.AddTag(k[0], "work"); err != nil {
+		t.Fatal(err)
+	}
+	before, err := cm.Read(k[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if found, err := cm.Recopy("missing", now.UnixMilli(), "kitty"); err != nil || found {
+		t.Errorf("Recopy(missing) = %v, %v; want not found", found, err)
+	}
+
+	later := now.Add(time.Hour).UnixMilli()
+	for i, app := range []string{"kitty", "firefox"} {
+		found, err := cm.Recopy(k[0], later+int64(i), app)
+		if err != nil || !found {
+			t.Fatalf("Recopy = %v, %v; want found", found, err)
+		}
+	}
+
+	after, err := cm.Read(k[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.Timestamp != later+1 {
+		t.Errorf("Timestamp = %d, want the last copy %d", after.Timestamp, later+1)
+	}
+	if after.FirstSeen != before.Timestamp {
+		t.Errorf("FirstSeen = %d, want the first copy %d", after.FirstSeen, before.Timestamp)
+	}
+	if after.CopyCount != 3 {
+		t.Errorf("CopyCount = %d, want 3", after.CopyCount)
+	}
+	if after.Application != "kitty" {
+		t.Errorf("Application = %q, want the first app it was re-copied in", after.Application)
+	}
+	if after.Content != before.Content || !after.Pinned || !reflect.DeepEqual(after.Tag, before.Tag) {
+		t.Errorf("re-copied entry = %+v, want content, pin and tags kept from %+v", after, before)
+	}
+
+	// Re-copying an unpinned entry moves it to the top of the unpinned ones.
+	if _, err := cm.Recopy(k[1], later+2, ""); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := queryAll(t, cm, ClipQuery{})
+	if want := []string{k[0], k[1], k[2]}; !reflect.DeepEqual(got, want) {
+		t.Errorf("order after re-copying = %v, want %v", got, want)
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/binary"
 	"fmt"
-	"reflect"
 	"rilaunch/pkg/config"
 	"strings"
 
@@ -33,7 +32,9 @@ func SetRefreshCallback(callback ClipboardEventCallback) {
 	refreshCallback = callback
 }
 
-func Record(ctx context.Context) error {
+// Run records the clipboard until ctx is done. Copies made while the
+// recorder is paused are dropped.
+func (r *Recorder) Run(ctx context.Context) error {
 	logger := util.GetLogInstance()
 	logger.Info().Msg("Clipboard recording started...")
 
@@ -42,21 +43,11 @@ func Record(ctx context.Context) error {
 		panic(err)
 	}
 
-	ch := goclipboard.Watch(ctx, goclipboard.FmtText)
+	ch := goclipboard.Watch(ctx, goclipboard.FmtText, goclipboard.FmtImage)
 
 	for incomingData := range ch {
-		var rawBytes []byte
-
-		val := reflect.ValueOf(incomingData)
-		if val.Kind() == reflect.Struct {
-			for i := 0; i < val.NumField(); i++ {
-				if val.Field(i).Kind() == reflect.Slice && val.Field(i).Type().Elem().Kind() == reflect.Uint8 {
-					rawBytes = val.Field(i).Bytes()
-					break
-				}
-			}
-		} else if val.Kind() == reflect.Slice {
-			rawBytes = val.Bytes()
+		if r.Paused() {
+			continue
 		}
 
 		clipDb := config.GetInstance()
@@ -64,23 +55,84 @@ func Record(ctx context.Context) error {
 			DB: clipDb.DB,
 		}
 
-		copiedStr := string(rawBytes)
-		if len(strings.TrimSpace(copiedStr)) == 0 {
+		settings := config.CurrentSettings()
+		source, known := currentSource()
+		if rule, ignored := source.ignoredBy(settings.ClipIgnoreApps); known && ignored {
+			logger.Info().Msgf("Not saving copy from %s, ignored by rule %q", source.Name, rule.App)
 			continue
 		}
 
-		timestamp := util.UnixMilli()
-		clipInfo := ClipInfo{
-			Timestamp: timestamp,
-			Content:   copiedStr,
-		}
-		hash := util.CalculateHash(copiedStr)
+		if incomingData.Format == goclipboard.FmtImage {
+			if err := recordImage(&clipm, incomingData.Bytes, source.Name); err != nil {
+				logger.Error().Err(err).Msg("Failed to save clipboard image")
+				continue
+			}
+		} else {
+			copiedStr := string(incomingData.Bytes)
+			if len(strings.TrimSpace(copiedStr)) == 0 {
+				continue
+			}
 
-		clipm.Create(hash, clipInfo)
+			timestamp := util.UnixMilli()
+			clipInfo := ClipInfo{
+				Application: source.Name,
+				Timestamp:   timestamp,
+				FirstSeen:   timestamp,
+				CopyCount:   1,
+				Content:     copiedStr,
+				ContentType: ContentTypeText,
+			}
+			hash := util.CalculateHash(copiedStr)
+
+			// A re-copied entry keeps its flags and tags, and is not checked
+			// for secrets again, so an unmarked entry stays unmarked. A
+			// re-copied secret must not be stored again in plaintext.
+			found := false
+			if hasSecrets, _ := clipm.HasSecrets(); hasSecrets {
+				if secretKey, err := SecretKeyFor(copiedStr); err == nil {
+					found, _ = clipm.Recopy(secretKey, timestamp, source.Name)
+				}
+			}
+			if !found {
+				var err error
+				if found, err = clipm.Recopy(hash, timestamp, source.Name); err != nil {
+					logger.Error().Err(err).Msg("Failed to update clipboard entry")
+					continue
+				}
+			}
+			if found {
+				logger.Info().Msg("Clipboard entry COPIED again!")
+				if refreshCallback != nil {
+					refreshCallback()
+				}
+				continue
+			}
 
-		str := util.CleanStr(copiedStr).StandardizeSpaces().TruncateText(10).ReplaceNewLine()
-		logger.Info().Msg(string(str + "... COPIED!"))
-		fmt.Printf("📋 Saving to clipdb: %s...\n", string(str))
+			if action := settings.ClipSecretAction; action != SecretActionOff {
+				if reason, ok := DetectSecret(copiedStr); ok {
+					if action == SecretActionSkip {
+						logger.Info().Msgf("Not saving copied %s", reason)
+						continue
+					}
+					clipInfo.SecretReason = reason
+					if err := clipm.CreateSecret(clipInfo); err != nil {
+						logger.Error().Err(err).Msg("Failed to save secret clipboard entry")
+						continue
+					}
+					logger.Info().Msgf("Saved copied %s as secret", reason)
+					if refreshCallback != nil {
+						refreshCallback()
+					}
+					continue
+				}
+			}
+
+			clipm.Create(hash, clipInfo)
+
+			str := util.CleanStr(copiedStr).StandardizeSpaces().TruncateText(10).ReplaceNewLine()
+			logger.Info().Msg(string(str + "... COPIED!"))
+			fmt.Printf("📋 Saving to clipdb: %s...\n", string(str))
+		}
 
 		if refreshCallback != nil {
 			refreshCallback()
@@ -88,4 +140,28 @@ func Record(ctx context.Context) error {
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// recordImage stores a copied image, keyed by the hash of its PNG bytes, or
+// records another copy of it.
+func recordImage(clipm *ClipM, data []byte, application string) error {
+	if len(data) == 0 {
+		return nil
+	}
+	timestamp := util.UnixMilli()
+	hash := util.CalculateHash(string(data))
+	if found, err := clipm.Recopy(hash, timestamp, application); err != nil || found {
+		return err
+	}
+	clipInfo, thumb, err := NewImageClip(data)
+	if err != nil {
+		return err
+	}
+	clipInfo.Timestamp, clipInfo.FirstSeen, clipInfo.CopyCount = timestamp, timestamp, 1
+	clipInfo.Application = application
+	if err := clipm.CreateImage(hash, clipInfo, data, thumb); err != nil {
+		return err
+	}
+	util.GetLogInstance().Info().Msg(clipInfo.Content + " COPIED!")
+	return nil
+}
@@ -0,0 +1,258 @@
+package clipm
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"math"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// What Record does with copied text that a detector recognizes as secret,
+// see config.Settings.ClipSecretAction.
+const (
+	SecretActionMark = "mark"
+	SecretActionSkip = "skip"
+	SecretActionOff  = "off"
+)
+
+// Detector recognizes secrets in copied text. Detect returns a short reason,
+// such as "GitHub token", when content holds a secret.
+type Detector interface {
+	Detect(content string) (reason string, ok bool)
+}
+
+// DetectorFunc adapts a function to a Detector.
+type DetectorFunc func(content string) (string, bool)
+
+func (f DetectorFunc) Detect(content string) (string, bool) { return f(content) }
+
+// maxDetectLength skips detection for long texts, which are documents or
+// code rather than a copied credential, and would be slow to scan.
+const maxDetectLength = 64 << 10
+
+var (
+	detectorsMu sync.RWMutex
+	detectors   = []Detector{
+		DetectorFunc(detectPEM),
+		DetectorFunc(detectToken),
+		DetectorFunc(detectJWT),
+		DetectorFunc(detectCardNumber),
+		DetectorFunc(detectHighEntropy),
+	}
+)
+
+// RegisterDetector adds a detector to the pipeline run on captured text.
+func RegisterDetector(d Detector) {
+	detectorsMu.Lock()
+	defer detectorsMu.Unlock()
+	detectors = append(detectors, d)
+}
+
+// DetectSecret runs the detectors in order and returns the reason given by
+// the first one that recognizes a secret.
+func DetectSecret(content string) (string, bool) {
+	if len(content) > maxDetectLength {
+		return "", false
+	}
+	detectorsMu.RLock()
+	defer detectorsMu.RUnlock()
+	for _, d := range detectors {
+		if reason, ok := d.Detect(content); ok {
+			return reason, true
+		}
+	}
+	return "", false
+}
+
+var pemPattern = regexp.MustCompile(`-----BEGIN ((?:[A-Z0-9]+ )*PRIVATE KEY(?: BLOCK)?)-----`)
+
+func detectPEM(content string) (string, bool) {
+	if m := pemPattern.FindStringSubmatch(content); m != nil {
+		return "PEM " + strings.ToLower(m[1]), true
+	}
+	return "", false
+}
+
+// tokenPatterns are credentials recognizable by their prefix.
+var tokenPatterns = []struct {
+	reason  string
+	pattern *regexp.Regexp
+}{
+	{"AWS access key", regexp.MustCompile(`\b(?:AKIA|ASIA|ABIA|ACCA)[A-Z0-9]{16}\b`)},
+	{"AWS secret key", regexp.MustCompile(`(?i)aws_secret_access_key\s*[=:]\s*["']?[A-Za-z0-9/+]{40}\b`)},
+	{"GitHub token", regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,255}\b`)},
+	{"GitHub token", regexp.MustCompile(`\bgithub_pat_[A-Za-z0-9_]{22,255}\b`)},
+	{"GitLab token", regexp.MustCompile(`\bglpat-[A-Za-z0-9_-]{20,}\b`)},
+	{"Slack token", regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}\b`)},
+	{"Slack webhook", regexp.MustCompile(`https://hooks\.slack\.com/services/T[A-Za-z0-9]+/B[A-Za-z0-9]+/[A-Za-z0-9]+`)},
+	{"Stripe key", regexp.MustCompile(`\b[sr]k_live_[A-Za-z0-9]{24,}\b`)},
+	{"Google API key", regexp.MustCompile(`\bAIza[A-Za-z0-9_-]{35}\b`)},
+	{"npm token", regexp.MustCompile(`\bnpm_[A-Za-z0-9]{36}\b`)},
+	{"OpenAI key", regexp.MustCompile(`\bsk-(?:proj-)?[A-Za-z0-9_-]{32,}\b`)},
+}
+
+func detectToken(content string) (string, bool) {
+	for _, t := range tokenPatterns {
+		if t.pattern.MatchString(content) {
+			return t.reason, true
+		}
+	}
+	return "", false
+}
+
+var jwtPattern = regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{5,}\.eyJ[A-Za-z0-9_-]{5,}\.[A-Za-z0-9_-]*`)
+
+// detectJWT recognizes JSON Web Tokens by a header that decodes to a JSON
+// object naming an algorithm.
+func detectJWT(content string) (string, bool) {
+	for _, token := range jwtPattern.FindAllString(content, -1) {
+		header, _, _ := strings.Cut(token, ".")
+		data, err := base64.RawURLEncoding.DecodeString(header)
+		if err != nil {
+			continue
+		}
+		var fields map[string]any
+		if json.Unmarshal(data, &fields) == nil && fields["alg"] != nil {
+			return "JWT", true
+		}
+	}
+	return "", false
+}
+
+var cardPattern = regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)
+
+// detectCardNumber recognizes payment card numbers: 13 to 19 digits, with
+// an issuer prefix and a valid Luhn checksum.
+func detectCardNumber(content string) (string, bool) {
+	for _, match := range cardPattern.FindAllString(content, -1) {
+		digits := strings.Map(func(r rune) rune {
+			if r >= '0' && r <= '9' {
+				return r
+			}
+			return -1
+		}, match)
+		if cardIssuer(digits) && luhnValid(digits) {
+			return "card number", true
+		}
+	}
+	return "", false
+}
+
+// cardIssuer reports whether digits start like a Visa, Mastercard, American
+// Express, Discover, JCB or Diners Club number of a valid length.
+func cardIssuer(digits string) bool {
+	n := len(digits)
+	switch {
+	case digits[0] == '4':
+		return n == 13 || n == 16 || n == 19
+	case digits[:2] >= "51" && digits[:2] <= "55", digits[:4] >= "2221" && digits[:4] <= "2720":
+		return n == 16
+	case digits[:2] == "34", digits[:2] == "37":
+		return n == 15
+	case digits[:4] == "6011", digits[:2] == "65", digits[:2] == "35":
+		return n >= 16
+	case digits[:2] == "36", digits[:2] == "38", digits[:3] >= "300" && digits[:3] <= "305":
+		return n == 14
+	}
+	return false
+}
+
+func luhnValid(digits string) bool {
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+const (
+	entropyMinLength = 16
+	entropyMaxLength = 512
+	// entropyThreshold, in bits per character, is above what words and
+	// identifiers reach, but below random base64 or mixed-class tokens.
+	// Short strings cannot reach it, so they are held to entropySlack
+	// below the maximum for their length instead.
+	entropyThreshold = 4.2
+	entropySlack     = 0.5
+)
+
+// detectHighEntropy recognizes a single random-looking token, such as a
+// generated password or an API key without a known prefix. It needs at
+// least three character classes, so hashes, UUIDs and identifiers pass.
+func detectHighEntropy(content string) (string, bool) {
+	token := strings.TrimSpace(content)
+	if len(token) < entropyMinLength || len(token) > entropyMaxLength || strings.ContainsAny(token, " \t\r\n") {
+		return "", false
+	}
+	if strings.Contains(token, "://") || strings.HasPrefix(token, "/") || strings.HasPrefix(token, "~") {
+		return "", false
+	}
+
+	var lower, upper, digit, other bool
+	for _, r := range token {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			other = true
+		}
+	}
+	classes := 0
+	for _, present := range []bool{lower, upper, digit, other} {
+		if present {
+			classes++
+		}
+	}
+	threshold := math.Min(entropyThreshold, math.Log2(float64(len(token)))-entropySlack)
+	if classes < 3 || shannonEntropy(token) < threshold || wordLike(token) {
+		return "", false
+	}
+	return "high-entropy string", true
+}
+
+// wordPattern splits camelCase and CONSTANT_CASE names into words.
+var wordPattern = regexp.MustCompile(`[A-Z]?[a-z]+|[A-Z]+`)
+
+// wordLike reports whether most of token is made of words of three or more
+// letters, as in "dnsWaitGroup.Add" or "MyProject_v2.3.1-beta". Random
+// strings of the same length rarely are.
+func wordLike(token string) bool {
+	letters, inWords := 0, 0
+	for _, word := range wordPattern.FindAllString(token, -1) {
+		letters += len(word)
+		if len(word) >= 3 {
+			inWords += len(word)
+		}
+	}
+	return letters > 0 && inWords*10 >= letters*6
+}
+
+func shannonEntropy(s string) float64 {
+	counts := make(map[rune]int)
+	n := 0
+	for _, r := range s {
+		counts[r]++
+		n++
+	}
+	var entropy float64
+	for _, c := range counts {
+		p := float64(c) / float64(n)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
@@ -0,0 +1,124 @@
+package clipm
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"rilaunch/pkg/config"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	ContentTypeText  = "text"
+	ContentTypeImage = "image"
+
+	// thumbnailSize bounds the longer side of image thumbnails.
+	thumbnailSize = 256
+)
+
+// NewImageClip decodes a PNG from the clipboard and returns its entry, with
+// a description as content so it can be searched, and its thumbnail.
+func NewImageClip(data []byte) (ClipInfo, []byte, error) {
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return ClipInfo{}, nil, fmt.Errorf("invalid clipboard image: %w", err)
+	}
+	b := img.Bounds()
+	clipInfo := ClipInfo{
+		ContentType: ContentTypeImage,
+		Content:     fmt.Sprintf("Image %d×%d", b.Dx(), b.Dy()),
+		Width:       b.Dx(),
+		Height:      b.Dy(),
+		Size:        len(data),
+	}
+
+	var thumb bytes.Buffer
+	if err := png.Encode(&thumb, thumbnail(img, thumbnailSize)); err != nil {
+		return ClipInfo{}, nil, err
+	}
+	return clipInfo, thumb.Bytes(), nil
+}
+
+// thumbnail scales img down so its longer side is at most size, averaging
+// the source pixels that fall into each thumbnail pixel.
+func thumbnail(img image.Image, size int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= size && h <= size {
+		return img
+	}
+	tw, th := size, h*size/w
+	if h > w {
+		tw, th = w*size/h, size
+	}
+	tw, th = max(tw, 1), max(th, 1)
+
+	dst := image.NewNRGBA(image.Rect(0, 0, tw, th))
+	for y := 0; y < th; y++ {
+		y0, y1 := b.Min.Y+y*h/th, b.Min.Y+max((y+1)*h/th, y*h/th+1)
+		for x := 0; x < tw; x++ {
+			x0, x1 := b.Min.X+x*w/tw, b.Min.X+max((x+1)*w/tw, x*w/tw+1)
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					c := color.NRGBA64Model.Convert(img.At(sx, sy)).(color.NRGBA64)
+					r, g, bl, a = r+uint64(c.R), g+uint64(c.G), bl+uint64(c.B), a+uint64(c.A)
+					n++
+				}
+			}
+			dst.SetNRGBA(x, y, color.NRGBA{
+				R: uint8(r / n >> 8), G: uint8(g / n >> 8), B: uint8(bl / n >> 8), A: uint8(a / n >> 8),
+			})
+		}
+	}
+	return dst
+}
+
+// CreateImage stores an image entry together with the image, as PNG, and
+// its thumbnail, all keyed by the hash of the image.
+func (clipm *ClipM) CreateImage(key string, clipInfo ClipInfo, data, thumb []byte) error {
+	return clipm.DB.Update(func(tx *bolt.Tx) error {
+		images := tx.Bucket(config.ClipImageBucket)
+		thumbs := tx.Bucket(config.ClipThumbBucket)
+		if images == nil || thumbs == nil {
+			return fmt.Errorf("clipInfo not found")
+		}
+		if err := images.Put([]byte(key), data); err != nil {
+			return err
+		}
+		if err := thumbs.Put([]byte(key), thumb); err != nil {
+			return err
+		}
+		return putClip(tx, []byte(key), clipInfo)
+	})
+}
+
+// ReadImage returns the PNG of an image entry.
+func (clipm *ClipM) ReadImage(key string) ([]byte, error) {
+	return clipm.readBlob(config.ClipImageBucket, key)
+}
+
+// ReadThumbnail returns the PNG thumbnail of an image entry.
+func (clipm *ClipM) ReadThumbnail(key string) ([]byte, error) {
+	return clipm.readBlob(config.ClipThumbBucket, key)
+}
+
+func (clipm *ClipM) readBlob(bucketName []byte, key string) ([]byte, error) {
+	var data []byte
+	err := clipm.DB.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketName)
+		if bucket == nil {
+			return fmt.Errorf("clipInfo not found")
+		}
+		v := bucket.Get([]byte(key))
+		if v == nil {
+			return fmt.Errorf("image not found: %s", key)
+		}
+		data = append([]byte(nil), v...)
+		return nil
+	})
+	return data, err
+}
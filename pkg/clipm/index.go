@@ -0,0 +1,120 @@
+package clipm
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"rilaunch/pkg/config"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// The index bucket holds a key per entry: its timestamp, big-endian so keys
+// sort by time, followed by the entry's key. The value flags pinned entries,
+// so they can be listed first without decoding every entry.
+
+const (
+	indexUnpinned byte = 0
+	indexPinned   byte = 1
+)
+
+func indexKey(timestamp int64, key []byte) []byte {
+	k := make([]byte, 8, 8+len(key))
+	binary.BigEndian.PutUint64(k, uint64(timestamp))
+	return append(k, key...)
+}
+
+// entryKeyOf returns the key of the entry an index key refers to.
+func entryKeyOf(indexKey []byte) []byte {
+	return indexKey[8:]
+}
+
+func indexValue(clipInfo ClipInfo) []byte {
+	if clipInfo.Pinned {
+		return []byte{indexPinned}
+	}
+	return []byte{indexUnpinned}
+}
+
+// putClip stores an entry and updates its index key.
+func putClip(tx *bolt.Tx, key []byte, clipInfo ClipInfo) error {
+	bucket := tx.Bucket(config.ClipBucket)
+	index := tx.Bucket(config.ClipIndexBucket)
+	if bucket == nil || index == nil {
+		return fmt.Errorf("clipInfo not found")
+	}
+	if err := unindex(bucket, index, key); err != nil {
+		return err
+	}
+	data, err := json.Marshal(clipInfo)
+	if err != nil {
+		return err
+	}
+	if err := bucket.Put(key, data); err != nil {
+		return err
+	}
+	return index.Put(indexKey(clipInfo.Timestamp, key), indexValue(clipInfo))
+}
+
+// deleteClip deletes an entry, with its index key and, for images, its image
+// and thumbnail.
+func deleteClip(tx *bolt.Tx, key []byte) error {
+	bucket := tx.Bucket(config.ClipBucket)
+	if bucket == nil {
+		return fmt.Errorf("clipInfo not found")
+	}
+	if index := tx.Bucket(config.ClipIndexBucket); index != nil {
+		if err := unindex(bucket, index, key); err != nil {
+			return err
+		}
+	}
+	for _, name := range [][]byte{config.ClipImageBucket, config.ClipThumbBucket} {
+		if b := tx.Bucket(name); b != nil {
+			if err := b.Delete(key); err != nil {
+				return err
+			}
+		}
+	}
+	return bucket.Delete(key)
+}
+
+// unindex deletes the index key of the stored entry with key, if any.
+func unindex(bucket, index *bolt.Bucket, key []byte) error {
+	old := bucket.Get(key)
+	if old == nil {
+		return nil
+	}
+	var clipInfo ClipInfo
+	json.Unmarshal(old, &clipInfo)
+	return index.Delete(indexKey(clipInfo.Timestamp, key))
+}
+
+// RebuildIndex rebuilds the index when it does not have a key for every
+// entry, as after upgrading from a version without it.
+func (clipm *ClipM) RebuildIndex() (bool, error) {
+	rebuilt := false
+	err := clipm.DB.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(config.ClipBucket)
+		if bucket == nil || tx.Bucket(config.ClipIndexBucket) == nil {
+			return fmt.Errorf("clipInfo not found")
+		}
+		if bucket.Stats().KeyN == tx.Bucket(config.ClipIndexBucket).Stats().KeyN {
+			return nil
+		}
+		if err := tx.DeleteBucket(config.ClipIndexBucket); err != nil {
+			return err
+		}
+		index, err := tx.CreateBucket(config.ClipIndexBucket)
+		if err != nil {
+			return err
+		}
+		rebuilt = true
+		return bucket.ForEach(func(k, v []byte) error {
+			// Entries that cannot be decoded are indexed as the oldest.
+			var clipInfo ClipInfo
+			json.Unmarshal(v, &clipInfo)
+			return index.Put(indexKey(clipInfo.Timestamp, k), indexValue(clipInfo))
+		})
+	})
+	return rebuilt, err
+}
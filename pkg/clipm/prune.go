@@ -0,0 +1,132 @@
+package clipm
+
+import (
+	"context"
+	"encoding/json"
+	"rilaunch/pkg/config"
+	"rilaunch/pkg/util"
+	"sort"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// pruneInterval is how often the pruner enforces the retention settings.
+const pruneInterval = 10 * time.Minute
+
+// RetentionPolicy bounds the clipboard history. Zero values disable a limit.
+type RetentionPolicy struct {
+	MaxEntries int
+	MaxAge     time.Duration
+	MaxBytes   int64
+}
+
+func RetentionFromSettings(s *config.Settings) RetentionPolicy {
+	return RetentionPolicy{
+		MaxEntries: s.ClipMaxEntries,
+		MaxAge:     time.Duration(s.ClipMaxAgeDays) * 24 * time.Hour,
+		MaxBytes:   s.ClipMaxBytes,
+	}
+}
+
+// Prune deletes the entries, and the images of image entries, that the
+// policy does not retain, oldest first, and returns how many it deleted.
+// Pinned entries are never deleted, and do not count towards the limits.
+func (clipm *ClipM) Prune(policy RetentionPolicy) (int, error) {
+	removed := 0
+	err := clipm.DB.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(config.ClipBucket)
+		if bucket == nil {
+			return nil
+		}
+		images := tx.Bucket(config.ClipImageBucket)
+		thumbs := tx.Bucket(config.ClipThumbBucket)
+
+		type entry struct {
+			key       []byte
+			timestamp int64
+			size      int64
+		}
+		var entries []entry
+		var total int64
+		bucket.ForEach(func(k, v []byte) error {
+			var clipInfo ClipInfo
+			if err := json.Unmarshal(v, &clipInfo); err != nil || clipInfo.Pinned {
+				return nil
+			}
+			e := entry{
+				key:       append([]byte(nil), k...),
+				timestamp: clipInfo.Timestamp,
+				size:      int64(len(v)),
+			}
+			if images != nil {
+				e.size += int64(len(images.Get(k)))
+			}
+			if thumbs != nil {
+				e.size += int64(len(thumbs.Get(k)))
+			}
+			entries = append(entries, e)
+			total += e.size
+			return nil
+		})
+
+		// Newest first, so the entries past a limit are at the end.
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].timestamp > entries[j].timestamp
+		})
+
+		var cutoff int64
+		if policy.MaxAge > 0 {
+			cutoff = time.Now().Add(-policy.MaxAge).UnixMilli()
+		}
+		count := len(entries)
+		for i := len(entries) - 1; i >= 0; i-- {
+			e := entries[i]
+			tooMany := policy.MaxEntries > 0 && count > policy.MaxEntries
+			tooBig := policy.MaxBytes > 0 && total > policy.MaxBytes
+			tooOld := cutoff > 0 && e.timestamp < cutoff
+			if !tooMany && !tooBig && !tooOld {
+				continue
+			}
+
+			if err := deleteClip(tx, e.key); err != nil {
+				return err
+			}
+			count--
+			total -= e.size
+			removed++
+		}
+		return nil
+	})
+	return removed, err
+}
+
+// RunPruner enforces the retention settings now and every pruneInterval
+// until ctx is done. Settings are re-read on every run, so changes apply
+// without a restart.
+func RunPruner(ctx context.Context) {
+	logger := util.GetLogInstance()
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+
+	for {
+		clipm := ClipM{
+			DB: config.GetInstance().DB,
+		}
+		removed, err := clipm.Prune(RetentionFromSettings(config.LoadSettings()))
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to prune clipboard history")
+		} else if removed > 0 {
+			logger.Info().Msgf("Pruned %d clipboard entries", removed)
+			if refreshCallback != nil {
+				refreshCallback()
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
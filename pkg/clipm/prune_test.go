@@ -0,0 +1,145 @@
+package clipm
+
+import (
+	"fmt"
+	"rilaunch/pkg/config"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// addClips stores n text entries of equal size, one minute apart, the
+// newest copied at now, and returns their keys, oldest first.
+func addClips(t *testing.T, cm *ClipM, n int, now time.Time, pinned func(i int) bool) []string {
+	t.Helper()
+	keys := make([]string, n)
+	for i := range n {
+		keys[i] = fmt.Sprintf("clip%03d", i)
+		clipInfo := ClipInfo{
+			Content:     fmt.Sprintf("entry %03d", i),
+			Timestamp:   now.Add(-time.Duration(n-1-i) * time.Minute).UnixMilli(),
+			ContentType: ContentTypeText,
+			Pinned:      pinned != nil && pinned(i),
+		}
+		if err := cm.Create(keys[i], clipInfo); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return keys
+}
+
+// remaining returns which of keys are still stored.
+func remaining(t *testing.T, cm *ClipM, keys []string) map[string]bool {
+	t.Helper()
+	left := make(map[string]bool)
+	cm.DB.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(config.ClipBucket)
+		for _, key := range keys {
+			if bucket.Get([]byte(key)) != nil {
+				left[key] = true
+			}
+		}
+		return nil
+	})
+	return left
+}
+
+func storedSize(t *testing.T, cm *ClipM, key string) int64 {
+	t.Helper()
+	var size int64
+	cm.DB.View(func(tx *bolt.Tx) error {
+		size = int64(len(tx.Bucket(config.ClipBucket).Get([]byte(key))))
+		return nil
+	})
+	return size
+}
+
+func TestPrune(t *testing.T) {
+	now := time.Now()
+	pinFirstTwo := func(i int) bool { return i < 2 }
+
+	tests := []struct {
+		name    string
+		pinned  func(i int) bool
+		policy  func(entrySize int64) RetentionPolicy
+		removed int
+		kept    []int
+	}{
+		{
+			name:    "no limits",
+			policy:  func(int64) RetentionPolicy { return RetentionPolicy{} },
+			removed: 0,
+			kept:    []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9},
+		},
+		{
+			name:    "count",
+			policy:  func(int64) RetentionPolicy { return RetentionPolicy{MaxEntries: 3} },
+			removed: 7,
+			kept:    []int{7, 8, 9},
+		},
+		{
+			name:    "age",
+			policy:  func(int64) RetentionPolicy { return RetentionPolicy{MaxAge: 4*time.Minute + 30*time.Second} },
+			removed: 5,
+			kept:    []int{5, 6, 7, 8, 9},
+		},
+		{
+			name:    "bytes",
+			policy:  func(size int64) RetentionPolicy { return RetentionPolicy{MaxBytes: 4*size + size/2} },
+			removed: 6,
+			kept:    []int{6, 7, 8, 9},
+		},
+		{
+			name:    "pinned do not count",
+			pinned:  pinFirstTwo,
+			policy:  func(int64) RetentionPolicy { return RetentionPolicy{MaxEntries: 3} },
+			removed: 5,
+			kept:    []int{0, 1, 7, 8, 9},
+		},
+		{
+			name:    "pinned do not take up bytes",
+			pinned:  pinFirstTwo,
+			policy:  func(size int64) RetentionPolicy { return RetentionPolicy{MaxBytes: 4*size + size/2} },
+			removed: 4,
+			kept:    []int{0, 1, 6, 7, 8, 9},
+		},
+		{
+			name:    "pinned survive age",
+			pinned:  pinFirstTwo,
+			policy:  func(int64) RetentionPolicy { return RetentionPolicy{MaxAge: 30 * time.Second} },
+			removed: 7,
+			kept:    []int{0, 1, 9},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cm := newTestClipM(t)
+			keys := addClips(t, cm, 10, now, tt.pinned)
+
+			removed, err := cm.Prune(tt.policy(storedSize(t, cm, keys[9])))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if removed != tt.removed {
+				t.Errorf("Prune removed %d entries, want %d", removed, tt.removed)
+			}
+			left := remaining(t, cm, keys)
+			if len(left) != len(tt.kept) {
+				t.Errorf("%d entries left, want %d", len(left), len(tt.kept))
+			}
+			for _, i := range tt.kept {
+				if !left[keys[i]] {
+					t.Errorf("%s was pruned", keys[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRetentionDefaultsOff(t *testing.T) {
+	t.Setenv("PAL_CONFIG_DIR", t.TempDir())
+	if policy := RetentionFromSettings(config.LoadSettings()); policy != (RetentionPolicy{}) {
+		t.Errorf("default retention policy = %+v, want no limits", policy)
+	}
+}
@@ -0,0 +1,231 @@
+package clipm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"rilaunch/pkg/config"
+	"rilaunch/pkg/fuzzy"
+	"strings"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Search queries can hold filter terms besides the text to match:
+// "app:<name>" keeps the entries copied in apps whose name starts with
+// <name>, so "app:fire" finds Firefox, and "tag:<tag>" those with the tag.
+const (
+	appFilterPrefix = "app:"
+	tagFilterPrefix = "tag:"
+)
+
+// clipQuery is a search query split into its filter terms, in lower case,
+// and the text to match.
+type clipQuery struct {
+	apps []string
+	tags []string
+	text string
+}
+
+func parseClipQuery(query string) clipQuery {
+	var q clipQuery
+	var words []string
+	for _, word := range strings.Fields(query) {
+		if value, ok := filterTerm(word, appFilterPrefix); ok {
+			q.apps = append(q.apps, value)
+		} else if value, ok := filterTerm(word, tagFilterPrefix); ok {
+			q.tags = append(q.tags, value)
+		} else {
+			words = append(words, word)
+		}
+	}
+	q.text = strings.Join(words, " ")
+	return q
+}
+
+func filterTerm(word, prefix string) (string, bool) {
+	if len(word) <= len(prefix) || !strings.EqualFold(word[:len(prefix)], prefix) {
+		return "", false
+	}
+	return strings.ToLower(word[len(prefix):]), true
+}
+
+// matchesFilters reports whether an entry was copied in one of the apps, if
+// any are given, and has all the tags.
+func (q clipQuery) matchesFilters(info ClipInfo) bool {
+	if len(q.apps) > 0 {
+		name := strings.ToLower(info.Application)
+		found := false
+		for _, app := range q.apps {
+			if strings.HasPrefix(name, app) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, tag := range q.tags {
+		if indexTag(info.Tag, tag) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+// ClipQuery selects a page of the clipboard history. Zero values do not
+// filter. Text may hold app: and tag: terms; the rest must fuzzy-match the
+// content. From and To bound the timestamp, in Unix milliseconds, From
+// inclusive and To exclusive. Cursor is the NextCursor of the previous page.
+type ClipQuery struct {
+	Text        string   `json:"text"`
+	App         string   `json:"app"`
+	Tags        []string `json:"tags"`
+	From        int64    `json:"from"`
+	To          int64    `json:"to"`
+	ContentType string   `json:"contentType"`
+	Cursor      string   `json:"cursor"`
+	Limit       int      `json:"limit"`
+}
+
+// ClipPage is a page of entries, pinned ones first, then newest first.
+// NextCursor is empty on the last page.
+type ClipPage struct {
+	Entries    []ClipInfo `json:"entries"`
+	NextCursor string     `json:"nextCursor,omitempty"`
+}
+
+// Cursors are the phase, pinned or unpinned entries, and the index key of
+// the last entry returned, so pages stay stable when entries are added.
+const (
+	cursorPinned   = "p"
+	cursorUnpinned = "u"
+)
+
+// Query returns a page of the entries matching q, walking the timestamp
+// index so only the entries up to the end of the page are read. Matches are
+// ordered by time rather than by how well they match the text.
+func (clipm *ClipM) Query(q ClipQuery) (ClipPage, error) {
+	filter := parseClipQuery(q.Text)
+	if q.App != "" {
+		filter.apps = append(filter.apps, strings.ToLower(q.App))
+	}
+	for _, tag := range q.Tags {
+		filter.tags = append(filter.tags, strings.ToLower(tag))
+	}
+	limit := q.Limit
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+	limit = min(limit, maxPageSize)
+
+	phase, after, err := parseCursor(q.Cursor)
+	if err != nil {
+		return ClipPage{}, err
+	}
+
+	page := ClipPage{Entries: []ClipInfo{}}
+	var last []byte
+	lastPhase := phase
+	err = clipm.DB.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(config.ClipBucket)
+		index := tx.Bucket(config.ClipIndexBucket)
+		if bucket == nil || index == nil {
+			return fmt.Errorf("clipInfo not found")
+		}
+
+		for ; phase != ""; phase, after = nextPhase(phase), nil {
+			wantPinned := phase == cursorPinned
+			c := index.Cursor()
+			for k, v := seekBefore(c, after, q.To); k != nil; k, v = c.Prev() {
+				if q.From > 0 && int64(binary.BigEndian.Uint64(k)) < q.From {
+					break
+				}
+				if (len(v) > 0 && v[0] == indexPinned) != wantPinned {
+					continue
+				}
+				key := entryKeyOf(k)
+				data := bucket.Get(key)
+				if data == nil {
+					continue
+				}
+				var clipInfo ClipInfo
+				if err := json.Unmarshal(data, &clipInfo); err != nil {
+					continue
+				}
+				clipInfo.Hash = string(key)
+				if clipInfo.ContentType == "" {
+					clipInfo.ContentType = ContentTypeText
+				}
+				if clipInfo.IsSecret {
+					clipInfo.Content, clipInfo.Encrypted = SecretMask, nil
+				}
+				if !filter.matchesFilters(clipInfo) ||
+					q.ContentType != "" && clipInfo.ContentType != q.ContentType {
+					continue
+				}
+				if _, ok := fuzzy.Score(filter.text, clipInfo.Content); !ok {
+					continue
+				}
+				if len(page.Entries) == limit {
+					// A further match: the page is full and not the last.
+					page.NextCursor = lastPhase + hex.EncodeToString(last)
+					return nil
+				}
+				page.Entries = append(page.Entries, clipInfo)
+				last, lastPhase = append([]byte(nil), k...), phase
+			}
+		}
+		return nil
+	})
+	return page, err
+}
+
+func parseCursor(cursor string) (phase string, after []byte, err error) {
+	if cursor == "" {
+		return cursorPinned, nil, nil
+	}
+	phase = cursor[:1]
+	if phase != cursorPinned && phase != cursorUnpinned {
+		return "", nil, fmt.Errorf("invalid cursor: %q", cursor)
+	}
+	after, err = hex.DecodeString(cursor[1:])
+	if err != nil || len(after) < 8 {
+		return "", nil, fmt.Errorf("invalid cursor: %q", cursor)
+	}
+	return phase, after, nil
+}
+
+func nextPhase(phase string) string {
+	if phase == cursorPinned {
+		return cursorUnpinned
+	}
+	return ""
+}
+
+// seekBefore positions c on the last index key before after, or with after
+// nil, before the timestamp to, if given, or else on the last key.
+func seekBefore(c *bolt.Cursor, after []byte, to int64) ([]byte, []byte) {
+	if after == nil && to > 0 {
+		after = indexKey(to, nil)
+	}
+	if after == nil {
+		return c.Last()
+	}
+	k, v := c.Seek(after)
+	if k == nil {
+		k, v = c.Last()
+	}
+	for k != nil && bytes.Compare(k, after) >= 0 {
+		k, v = c.Prev()
+	}
+	return k, v
+}
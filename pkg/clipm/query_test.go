@@ -0,0 +1,189 @@
+package clipm
+
+import (
+	"reflect"
+	"rilaunch/pkg/config"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// queryAll follows the cursors of q to the last page and returns the keys
+// of all entries, and the number of pages.
+func queryAll(t *testing.T, cm *ClipM, q ClipQuery) ([]string, int) {
+	t.Helper()
+	var keys []string
+	pages := 0
+	for {
+		page, err := cm.Query(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages++
+		for _, e := range page.Entries {
+			keys = append(keys, e.Hash)
+		}
+		if page.NextCursor == "" {
+			return keys, pages
+		}
+		if pages > 100 {
+			t.Fatal("query does not end")
+		}
+		q.Cursor = page.NextCursor
+	}
+}
+
+func pageKeys(page ClipPage) []string {
+	keys := make([]string, len(page.Entries))
+	for i, e := range page.Entries {
+		keys[i] = e.Hash
+	}
+	return keys
+}
+
+func TestQueryPagination(t *testing.T) {
+	cm := newTestClipM(t)
+	k := addClips(t, cm, 10, time.Now(), nil)
+	for _, i := range []int{2, 7} {
+		if err := cm.Pin(k[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []string{k[7], k[2], k[9], k[8], k[6], k[5], k[4], k[3], k[1], k[0]}
+
+	for _, limit := range []int{1, 2, 3, 4, 10, 50} {
+		got, pages := queryAll(t, cm, ClipQuery{Limit: limit})
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("limit %d: got %v, want %v", limit, got, want)
+		}
+		if wantPages := (len(want) + limit - 1) / limit; pages != wantPages {
+			t.Errorf("limit %d: %d pages, want %d", limit, pages, wantPages)
+		}
+	}
+
+	// The page boundary falls between the pinned and the unpinned entries.
+	page, err := cm.Query(ClipQuery{Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.NextCursor == "" || page.NextCursor[:1] != cursorPinned {
+		t.Errorf("cursor after the pinned entries = %q, want a pinned cursor", page.NextCursor)
+	}
+
+	for _, cursor := range []string{"x00", "p", "pzz", "u0102"} {
+		if _, err := cm.Query(ClipQuery{Cursor: cursor}); err == nil {
+			t.Errorf("cursor %q: expected an error", cursor)
+		}
+	}
+}
+
+func TestQueryCursorAfterInsert(t *testing.T) {
+	cm := newTestClipM(t)
+	now := time.Now()
+	k := addClips(t, cm, 6, now, nil)
+
+	first, err := cm.Query(ClipQuery{Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := pageKeys(first); !reflect.DeepEqual(got, []string{k[5], k[4]}) {
+		t.Fatalf("first page = %v", got)
+	}
+
+	// A newer entry, and a newer copy of an entry on the next page.
+	if err := cm.Create("newer", ClipInfo{Content: "newer", Timestamp: now.Add(time.Minute).UnixMilli()}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cm.Recopy(k[3], now.Add(2*time.Minute).UnixMilli(), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	for range 2 {
+		next, err := cm.Query(ClipQuery{Limit: 2, Cursor: first.NextCursor})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := pageKeys(next); !reflect.DeepEqual(got, []string{k[2], k[1]}) {
+			t.Errorf("next page = %v, want %v", got, []string{k[2], k[1]})
+		}
+	}
+
+	got, _ := queryAll(t, cm, ClipQuery{Limit: 4})
+	if want := []string{k[3], "newer", k[5], k[4], k[2], k[1], k[0]}; !reflect.DeepEqual(got, want) {
+		t.Errorf("after insert got %v, want %v", got, want)
+	}
+}
+
+func TestQueryFilters(t *testing.T) {
+	cm := newTestClipM(t)
+	now := time.Now().UnixMilli()
+	entries := []struct {
+		key  string
+		app  string
+		tags []string
+		text string
+		kind string
+	}{
+		{"a", "Firefox", []string{"work"}, "meeting notes", ContentTypeText},
+		{"b", "Firefox", nil, "recipe for bread", ContentTypeText},
+		{"c", "kitty", []string{"Work", "shell"}, "git push origin main", ContentTypeText},
+		{"d", "kitty", []string{"shell"}, "ls -la", ContentTypeText},
+		{"e", "Firefox Developer Edition", []string{"work"}, "screenshot.png", ContentTypeImage},
+	}
+	for i, e := range entries {
+		clipInfo := ClipInfo{Application: e.app, Tag: e.tags, Content: e.text, ContentType: e.kind, Timestamp: now + int64(i)}
+		if err := cm.Create(e.key, clipInfo); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		query ClipQuery
+		want  []string
+	}{
+		{ClipQuery{}, []string{"e", "d", "c", "b", "a"}},
+		{ClipQuery{Text: "app:firefox"}, []string{"e", "b", "a"}},
+		{ClipQuery{Text: "APP:Kit"}, []string{"d", "c"}},
+		{ClipQuery{Text: "app:fire app:kitty"}, []string{"e", "d", "c", "b", "a"}},
+		{ClipQuery{Text: "tag:work"}, []string{"e", "c", "a"}},
+		{ClipQuery{Text: "tag:work tag:shell"}, []string{"c"}},
+		{ClipQuery{Text: "app:kitty tag:work"}, []string{"c"}},
+		{ClipQuery{Text: "tag:work notes"}, []string{"a"}},
+		{ClipQuery{Text: "tag:missing"}, nil},
+		{ClipQuery{Text: "app:"}, nil},
+		{ClipQuery{App: "firefox", Tags: []string{"WORK"}}, []string{"e", "a"}},
+		{ClipQuery{Text: "tag:work", ContentType: ContentTypeText}, []string{"c", "a"}},
+		{ClipQuery{From: now + 1, To: now + 4}, []string{"d", "c", "b"}},
+	}
+	for _, tt := range tests {
+		got, _ := queryAll(t, cm, tt.query)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Query(%+v) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestRebuildIndex(t *testing.T) {
+	cm := newTestClipM(t)
+	k := addClips(t, cm, 5, time.Now(), func(i int) bool { return i == 1 })
+
+	if rebuilt, err := cm.RebuildIndex(); err != nil || rebuilt {
+		t.Fatalf("RebuildIndex() = %v, %v; want an up-to-date index", rebuilt, err)
+	}
+	err := cm.DB.Update(func(tx *bolt.Tx) error {
+		tx.DeleteBucket(config.ClipIndexBucket)
+		_, err := tx.CreateBucket(config.ClipIndexBucket)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rebuilt, err := cm.RebuildIndex(); err != nil || !rebuilt {
+		t.Fatalf("RebuildIndex() = %v, %v; want a rebuilt index", rebuilt, err)
+	}
+	got, _ := queryAll(t, cm, ClipQuery{})
+	if want := []string{k[1], k[4], k[3], k[2], k[0]}; !reflect.DeepEqual(got, want) {
+		t.Errorf("after rebuild got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,135 @@
+package clipm
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"rilaunch/pkg/config"
+	"rilaunch/pkg/util"
+	"sync"
+	"time"
+)
+
+// RecorderState tells whether clipboard recording is paused. PausedUntil is
+// when a timed pause ends, in Unix milliseconds, or 0 for a pause that
+// lasts until Resume.
+type RecorderState struct {
+	Paused      bool  `json:"paused"`
+	PausedUntil int64 `json:"pausedUntil,omitempty"`
+}
+
+type RecorderStateCallback func(RecorderState)
+
+var stateCallback RecorderStateCallback
+
+// SetRecorderStateCallback is called whenever recording is paused or
+// resumed, including when a timed pause ends.
+func SetRecorderStateCallback(callback RecorderStateCallback) {
+	stateCallback = callback
+}
+
+// Recorder records the clipboard history while it is not paused. Its state
+// is kept in recorder.json, so a pause survives restarts.
+type Recorder struct {
+	mu     sync.Mutex
+	state  RecorderState
+	resume *time.Timer
+}
+
+// NewRecorder returns a recorder in the state it was left in.
+func NewRecorder() *Recorder {
+	r := &Recorder{}
+	data, err := os.ReadFile(recorderStatePath())
+	if err == nil {
+		if err := json.Unmarshal(data, &r.state); err != nil {
+			util.GetLogInstance().Error().Err(err).Msg("Failed to read clipboard recorder state")
+		}
+	}
+	r.mu.Lock()
+	r.scheduleResume()
+	r.mu.Unlock()
+	return r
+}
+
+func recorderStatePath() string {
+	dir, _ := config.GetDefaultConfigDir()
+	return filepath.Join(dir, "recorder.json")
+}
+
+// State returns whether recording is paused.
+func (r *Recorder) State() RecorderState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.state
+}
+
+// Paused reports whether copies are currently dropped.
+func (r *Recorder) Paused() bool {
+	return r.State().Paused
+}
+
+// Pause stops recording until Resume.
+func (r *Recorder) Pause() error {
+	return r.setState(RecorderState{Paused: true})
+}
+
+// PauseFor stops recording for d, or until Resume if that comes first.
+func (r *Recorder) PauseFor(d time.Duration) error {
+	return r.setState(RecorderState{Paused: true, PausedUntil: time.Now().Add(d).UnixMilli()})
+}
+
+// Resume starts recording again.
+func (r *Recorder) Resume() error {
+	return r.setState(RecorderState{})
+}
+
+// Toggle pauses recording if it is running and resumes it otherwise.
+func (r *Recorder) Toggle() error {
+	if r.Paused() {
+		return r.Resume()
+	}
+	return r.Pause()
+}
+
+func (r *Recorder) setState(state RecorderState) error {
+	r.mu.Lock()
+	r.state = state
+	r.scheduleResume()
+	state = r.state
+	data, err := json.Marshal(state)
+	if err == nil {
+		err = os.WriteFile(recorderStatePath(), data, 0o600)
+	}
+	r.mu.Unlock()
+
+	if stateCallback != nil {
+		stateCallback(state)
+	}
+	return err
+}
+
+// scheduleResume arms the timer ending a timed pause, replacing any earlier
+// one, and ends a pause that is already over. r.mu must be held.
+func (r *Recorder) scheduleResume() {
+	if r.resume != nil {
+		r.resume.Stop()
+		r.resume = nil
+	}
+	if !r.state.Paused || r.state.PausedUntil == 0 {
+		return
+	}
+	left := time.Until(time.UnixMilli(r.state.PausedUntil))
+	if left <= 0 {
+		r.state = RecorderState{}
+		return
+	}
+	until := r.state.PausedUntil
+	r.resume = time.AfterFunc(left, func() {
+		// A Pause or Resume since then replaced this pause.
+		if r.State().PausedUntil == until {
+			if err := r.Resume(); err != nil {
+				util.GetLogInstance().Error().Err(err).Msg("Failed to save clipboard recorder state")
+			}
+		}
+	})
+}
@@ -0,0 +1,91 @@
+package clipm
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+)
+
+func newTestRecorder(t *testing.T) (*Recorder, <-chan RecorderState) {
+	t.Helper()
+	t.Setenv("PAL_CONFIG_DIR", t.TempDir())
+	states := make(chan RecorderState, 16)
+	SetRecorderStateCallback(func(s RecorderState) { states <- s })
+	t.Cleanup(func() { SetRecorderStateCallback(nil) })
+	return NewRecorder(), states
+}
+
+func TestRecorderPauseResume(t *testing.T) {
+	r, states := newTestRecorder(t)
+	if r.Paused() {
+		t.Fatal("new recorder is paused")
+	}
+
+	if err := r.Toggle(); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-states; !got.Paused || got.PausedUntil != 0 {
+		t.Errorf("state after Toggle = %+v, want paused until resumed", got)
+	}
+
+	// The pause survives a restart.
+	if !NewRecorder().Paused() {
+		t.Error("pause not restored by NewRecorder")
+	}
+
+	if err := r.Toggle(); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-states; got.Paused {
+		t.Errorf("state after second Toggle = %+v, want recording", got)
+	}
+	if NewRecorder().Paused() {
+		t.Error("resume not restored by NewRecorder")
+	}
+}
+
+func TestRecorderPauseFor(t *testing.T) {
+	r, states := newTestRecorder(t)
+
+	if err := r.PauseFor(50 * time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-states; !got.Paused || got.PausedUntil == 0 {
+		t.Fatalf("state after PauseFor = %+v, want a timed pause", got)
+	}
+	select {
+	case got := <-states:
+		if got.Paused {
+			t.Errorf("state when the pause ended = %+v, want recording", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed pause did not end")
+	}
+	if r.Paused() {
+		t.Error("still paused after the timed pause ended")
+	}
+
+	// A pause until resumed replaces a timed one.
+	if err := r.PauseFor(50 * time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Pause(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(150 * time.Millisecond)
+	if !r.Paused() {
+		t.Error("timed resume ended a later pause")
+	}
+}
+
+func TestRecorderExpiredPause(t *testing.T) {
+	t.Setenv("PAL_CONFIG_DIR", t.TempDir())
+	data, _ := json.Marshal(RecorderState{Paused: true, PausedUntil: time.Now().Add(-time.Minute).UnixMilli()})
+	if err := os.WriteFile(recorderStatePath(), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if NewRecorder().Paused() {
+		t.Error("pause that ended while stopped was restored")
+	}
+}
@@ -0,0 +1,397 @@
+package clipm
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"rilaunch/pkg/config"
+	"rilaunch/pkg/util"
+	"runtime"
+	"sync"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Secret entries are encrypted at rest with AES-256-GCM. The key comes from
+// the PAL_CLIP_PASSPHRASE environment variable, stretched with PBKDF2 and a
+// salt kept in clip.salt, or else from the random key in clip.key. Both
+// files live in the config directory and are only readable by the user.
+
+// SecretMask replaces the content of secret entries in listings.
+const SecretMask = "••••••••"
+
+const (
+	passphraseEnv    = "PAL_CLIP_PASSPHRASE"
+	pbkdf2Iterations = 600000
+	// secretKeyPrefix marks the bolt keys of secret entries, which are an
+	// HMAC of the content rather than its plain hash, so the database does
+	// not hold a dictionary-checkable hash of the secret either.
+	secretKeyPrefix = "s:"
+)
+
+var errNotSecret = errors.New("entry is not secret")
+
+type secretKeys struct {
+	aead  cipher.AEAD
+	idKey []byte
+}
+
+var (
+	keysMu sync.Mutex
+	keys   *secretKeys
+)
+
+// loadSecretKeys returns the encryption and ID keys, creating the key file
+// on first use.
+func loadSecretKeys() (*secretKeys, error) {
+	keysMu.Lock()
+	defer keysMu.Unlock()
+	if keys != nil {
+		return keys, nil
+	}
+
+	dir, err := config.GetDefaultConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	var master []byte
+	if passphrase := os.Getenv(passphraseEnv); passphrase != "" {
+		salt, err := readOrCreateKeyFile(filepath.Join(dir, "clip.salt"), 16)
+		if err != nil {
+			return nil, err
+		}
+		master, err = pbkdf2.Key(sha256.New, passphrase, salt, pbkdf2Iterations, 32)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		master, err = readOrCreateKeyFile(filepath.Join(dir, "clip.key"), 32)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	encKey, err := hkdf.Key(sha256.New, master, nil, "rilaunch clipboard encryption", 32)
+	if err != nil {
+		return nil, err
+	}
+	idKey, err := hkdf.Key(sha256.New, master, nil, "rilaunch clipboard id", 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(encKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	keys = &secretKeys{aead: aead, idKey: idKey}
+	return keys, nil
+}
+
+// readOrCreateKeyFile reads size random bytes from path, creating the file
+// with mode 0600 if it does not exist. Looser permissions are tightened.
+func readOrCreateKeyFile(path string, size int) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		data = make([]byte, size)
+		if _, err := rand.Read(data); err != nil {
+			return nil, err
+		}
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := f.Write(data); err != nil {
+			f.Close()
+			os.Remove(path)
+			return nil, err
+		}
+		return data, f.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(data) != size {
+		return nil, fmt.Errorf("%s: expected %d bytes, got %d", path, size, len(data))
+	}
+	if info, err := os.Stat(path); err == nil && runtime.GOOS != "windows" && info.Mode().Perm()&0o077 != 0 {
+		fmt.Printf("Warning: %s was accessible by other users, restricting it to 0600\n", path)
+		if err := os.Chmod(path, 0o600); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+func (k *secretKeys) seal(plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, k.aead.NonceSize(), k.aead.NonceSize()+len(plaintext)+k.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return k.aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func (k *secretKeys) open(sealed []byte) ([]byte, error) {
+	n := k.aead.NonceSize()
+	if len(sealed) < n {
+		return nil, fmt.Errorf("encrypted entry is truncated")
+	}
+	plaintext, err := k.aead.Open(nil, sealed[:n], sealed[n:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decrypt entry, wrong key or passphrase: %w", err)
+	}
+	return plaintext, nil
+}
+
+func (k *secretKeys) entryKey(content string) string {
+	mac := hmac.New(sha256.New, k.idKey)
+	mac.Write([]byte(content))
+	return secretKeyPrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// SecretKeyFor returns the key a secret entry with content would be stored
+// under, so a re-copied secret is recognized instead of stored in plaintext.
+func SecretKeyFor(content string) (string, error) {
+	k, err := loadSecretKeys()
+	if err != nil {
+		return "", err
+	}
+	return k.entryKey(content), nil
+}
+
+// HasSecrets reports whether any entry is stored encrypted. Loading the keys
+// creates the key file or runs PBKDF2, so callers that only need them to
+// look up existing secrets check this first.
+func (clipm *ClipM) HasSecrets() (bool, error) {
+	found := false
+	err := clipm.DB.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(config.ClipBucket)
+		if bucket == nil {
+			return nil
+		}
+		key, _ := bucket.Cursor().Seek([]byte(secretKeyPrefix))
+		found = bytes.HasPrefix(key, []byte(secretKeyPrefix))
+		return nil
+	})
+	return found, err
+}
+
+// MarkSecret toggles whether an entry is secret, encrypting or decrypting
+// its content in place, and returns the entry's new key. An entry already
+// stored under that key is merged into it. Images cannot be marked secret.
+func (clipm *ClipM) MarkSecret(key string) (string, error) {
+	k, err := loadSecretKeys()
+	if err != nil {
+		return "", err
+	}
+
+	newKey := key
+	err = clipm.DB.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(config.ClipBucket)
+		if bucket == nil {
+			return fmt.Errorf("clipInfo not found")
+		}
+		var clipInfo ClipInfo
+		data := bucket.Get([]byte(key))
+		if data == nil {
+			return fmt.Errorf("clipboard entry not found: %s", key)
+		}
+		if err := json.Unmarshal(data, &clipInfo); err != nil {
+			return err
+		}
+		if clipInfo.ContentType == ContentTypeImage {
+			return fmt.Errorf("images cannot be marked secret")
+		}
+
+		if clipInfo.IsSecret {
+			content, err := clipm.secretContent(k, &clipInfo)
+			if err != nil {
+				return err
+			}
+			clipInfo.IsSecret, clipInfo.Content, clipInfo.Encrypted, clipInfo.SecretReason = false, content, nil, ""
+			newKey = util.CalculateHash(content)
+		} else {
+			if err := encryptClip(k, &clipInfo); err != nil {
+				return err
+			}
+			newKey = k.entryKey(clipInfo.Content)
+			clipInfo.Content = ""
+		}
+
+		// The same text may have been copied again, and stored under the
+		// new key, while the entry was secret or not.
+		if data := bucket.Get([]byte(newKey)); data != nil {
+			var existing ClipInfo
+			if err := json.Unmarshal(data, &existing); err == nil {
+				mergeClip(&clipInfo, existing)
+			}
+		}
+
+		if err := deleteClip(tx, []byte(key)); err != nil {
+			return err
+		}
+		return putClip(tx, []byte(newKey), clipInfo)
+	})
+	if err == nil {
+		requestPlaintextCleanup()
+	}
+	return newKey, err
+}
+
+// mergeClip merges other, an entry with the same content, into clipInfo:
+// the copies of both are counted, the later copy's time and app are kept,
+// and the entry keeps the tags of both and is pinned if either was.
+func mergeClip(clipInfo *ClipInfo, other ClipInfo) {
+	firstSeen := func(c ClipInfo) int64 {
+		if c.FirstSeen != 0 {
+			return c.FirstSeen
+		}
+		return c.Timestamp
+	}
+	clipInfo.FirstSeen = min(firstSeen(*clipInfo), firstSeen(other))
+	clipInfo.CopyCount = max(clipInfo.CopyCount, 1) + max(other.CopyCount, 1)
+	if other.Timestamp > clipInfo.Timestamp {
+		clipInfo.Timestamp = other.Timestamp
+		if other.Application != "" {
+			clipInfo.Application = other.Application
+		}
+	}
+	clipInfo.Pinned = clipInfo.Pinned || other.Pinned
+	for _, tag := range other.Tag {
+		if indexTag(clipInfo.Tag, tag) < 0 {
+			clipInfo.Tag = append(clipInfo.Tag, tag)
+		}
+	}
+}
+
+// CreateSecret stores a new text entry encrypted, as MarkSecret would, but
+// without its plaintext ever reaching the database.
+func (clipm *ClipM) CreateSecret(clipInfo ClipInfo) error {
+	k, err := loadSecretKeys()
+	if err != nil {
+		return err
+	}
+	if err := encryptClip(k, &clipInfo); err != nil {
+		return err
+	}
+	key := k.entryKey(clipInfo.Content)
+	clipInfo.Content = ""
+	return clipm.Create(key, clipInfo)
+}
+
+// requestPlaintextCleanup has the database compacted at the next startup:
+// bolt does not clear freed pages, which still hold the plaintext.
+func requestPlaintextCleanup() {
+	if err := config.RequestCompaction(); err != nil {
+		fmt.Printf("Warning: failed to schedule database compaction: %v\n", err)
+	}
+}
+
+// encryptClip seals the content of a text entry and marks it secret. The
+// plaintext Content is left for the caller to derive the key from.
+func encryptClip(k *secretKeys, clipInfo *ClipInfo) error {
+	sealed, err := k.seal([]byte(clipInfo.Content))
+	if err != nil {
+		return err
+	}
+	clipInfo.IsSecret, clipInfo.Encrypted = true, sealed
+	return nil
+}
+
+// secretContent decrypts a secret entry. Entries marked secret before
+// encryption existed still hold their content in plaintext.
+func (clipm *ClipM) secretContent(k *secretKeys, clipInfo *ClipInfo) (string, error) {
+	if !clipInfo.IsSecret {
+		return "", errNotSecret
+	}
+	if clipInfo.Encrypted == nil {
+		return clipInfo.Content, nil
+	}
+	plaintext, err := k.open(clipInfo.Encrypted)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// ReadSecret returns the decrypted content of a secret entry.
+func (clipm *ClipM) ReadSecret(key string) (string, error) {
+	k, err := loadSecretKeys()
+	if err != nil {
+		return "", err
+	}
+	clipInfo, err := clipm.Read(key)
+	if err != nil {
+		return "", err
+	}
+	return clipm.secretContent(k, clipInfo)
+}
+
+// EncryptLegacySecrets encrypts the entries that were marked secret while
+// secrets were still stored in plaintext.
+func (clipm *ClipM) EncryptLegacySecrets() (int, error) {
+	// The keys are only loaded once there is an entry to encrypt.
+	var k *secretKeys
+	encrypted := 0
+	err := clipm.DB.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(config.ClipBucket)
+		if bucket == nil {
+			return nil
+		}
+		updates := make(map[string]ClipInfo)
+		var stale []string
+		err := bucket.ForEach(func(key, v []byte) error {
+			var clipInfo ClipInfo
+			if err := json.Unmarshal(v, &clipInfo); err != nil || !clipInfo.IsSecret || clipInfo.Encrypted != nil {
+				return nil
+			}
+			if k == nil {
+				var err error
+				if k, err = loadSecretKeys(); err != nil {
+					return err
+				}
+			}
+			if err := encryptClip(k, &clipInfo); err != nil {
+				return err
+			}
+			newKey := k.entryKey(clipInfo.Content)
+			clipInfo.Content = ""
+			stale = append(stale, string(key))
+			updates[newKey] = clipInfo
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, key := range stale {
+			if err := deleteClip(tx, []byte(key)); err != nil {
+				return err
+			}
+		}
+		for key, clipInfo := range updates {
+			if err := putClip(tx, []byte(key), clipInfo); err != nil {
+				return err
+			}
+		}
+		encrypted = len(updates)
+		return nil
+	})
+	if err == nil && encrypted > 0 {
+		requestPlaintextCleanup()
+	}
+	return encrypted, err
+}
@@ -0,0 +1,162 @@
+package clipm
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"rilaunch/pkg/config"
+	"rilaunch/pkg/util"
+	"testing"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func newTestClipM(t *testing.T) *ClipM {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("PAL_CONFIG_DIR", dir)
+	t.Setenv(passphraseEnv, "")
+	keysMu.Lock()
+	keys = nil
+	keysMu.Unlock()
+
+	db, err := bolt.Open(filepath.Join(dir, "palcb.db"), 0o600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{config.ClipBucket, config.ClipImageBucket, config.ClipThumbBucket, config.ClipIndexBucket} {
+			if _, err := tx.CreateBucket(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &ClipM{DB: db}
+}
+
+func keyFileExists(t *testing.T) bool {
+	t.Helper()
+	_, err := os.Stat(filepath.Join(os.Getenv("PAL_CONFIG_DIR"), "clip.key"))
+	return err == nil
+}
+
+func TestSecretKeysLoadedOnDemand(t *testing.T) {
+	cm := newTestClipM(t)
+	const content = "hunter2hunter2"
+	if err := cm.Create(util.CalculateHash(content), ClipInfo{Content: content, Timestamp: 1, ContentType: ContentTypeText}); err != nil {
+		t.Fatal(err)
+	}
+
+	if has, err := cm.HasSecrets(); err != nil || has {
+		t.Fatalf("HasSecrets() = %v, %v; want false", has, err)
+	}
+	if n, err := cm.EncryptLegacySecrets(); err != nil || n != 0 {
+		t.Fatalf("EncryptLegacySecrets() = %d, %v; want 0", n, err)
+	}
+	if keyFileExists(t) {
+		t.Fatal("clip.key created without any secret entry")
+	}
+
+	key, err := cm.MarkSecret(util.CalculateHash(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, _ := SecretKeyFor(content); key != want {
+		t.Errorf("MarkSecret returned %q, want %q", key, want)
+	}
+	if has, _ := cm.HasSecrets(); !has {
+		t.Error("HasSecrets() = false after marking an entry secret")
+	}
+	if !keyFileExists(t) {
+		t.Error("clip.key not created when marking an entry secret")
+	}
+	if got, err := cm.ReadSecret(key); err != nil || got != content {
+		t.Errorf("ReadSecret(%q) = %q, %v; want %q", key, got, err, content)
+	}
+
+	key, err = cm.MarkSecret(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := util.CalculateHash(content); key != want {
+		t.Errorf("unmarking returned %q, want %q", key, want)
+	}
+	if has, _ := cm.HasSecrets(); has {
+		t.Error("HasSecrets() = true after unmarking the only secret entry")
+	}
+}
+
+func TestEncryptLegacySecrets(t *testing.T) {
+	cm := newTestClipM(t)
+	const content = "legacy secret"
+	legacy := ClipInfo{Content: content, IsSecret: true, Timestamp: 1, ContentType: ContentTypeText}
+	if err := cm.Create(util.CalculateHash(content), legacy); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := cm.EncryptLegacySecrets(); err != nil || n != 1 {
+		t.Fatalf("EncryptLegacySecrets() = %d, %v; want 1", n, err)
+	}
+	key, _ := SecretKeyFor(content)
+	if got, err := cm.ReadSecret(key); err != nil || got != content {
+		t.Errorf("ReadSecret = %q, %v; want %q", got, err, content)
+	}
+}
+
+func TestMarkSecretMergesCollision(t *testing.T) {
+	cm := newTestClipM(t)
+	const content = "correct horse battery staple"
+	hash := util.CalculateHash(content)
+	if err := cm.Create(hash, ClipInfo{Content: content, Timestamp: 100, FirstSeen: 50, CopyCount: 2, Tag: []string{"old"}, ContentType: ContentTypeText}); err != nil {
+		t.Fatal(err)
+	}
+	secretKey, err := cm.MarkSecret(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The same text is copied again while the entry is secret, and the copy
+	// is tagged and pinned.
+	plain := ClipInfo{Content: content, Application: "kitty", Timestamp: 300, FirstSeen: 300, CopyCount: 1, Tag: []string{"new", "OLD"}, Pinned: true, ContentType: ContentTypeText}
+	if err := cm.Create(hash, plain); err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := cm.MarkSecret(secretKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key != hash {
+		t.Fatalf("unmarking returned %q, want %q", key, hash)
+	}
+	got, err := cm.Read(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ClipInfo{
+		Content:     content,
+		Application: "kitty",
+		Timestamp:   300,
+		FirstSeen:   50,
+		CopyCount:   3,
+		Tag:         []string{"old", "new"},
+		Pinned:      true,
+		ContentType: ContentTypeText,
+	}
+	if !reflect.DeepEqual(*got, want) {
+		t.Errorf("merged entry = %+v, want %+v", *got, want)
+	}
+
+	page, err := cm.Query(ClipQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys := pageKeys(page); !reflect.DeepEqual(keys, []string{hash}) {
+		t.Errorf("listed %v, want only %q", keys, hash)
+	}
+}
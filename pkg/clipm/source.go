@@ -0,0 +1,31 @@
+package clipm
+
+import (
+	"rilaunch/pkg/config"
+	"strings"
+)
+
+// SourceApp is the application a copy was made in. Name, the WM_CLASS class
+// on X11, is stored as ClipInfo.Application; the window title is only used
+// to match ignore rules and is never stored.
+type SourceApp struct {
+	Name     string
+	Instance string
+	Title    string
+}
+
+// ignoredBy returns the first rule that matches the app, if any.
+func (src SourceApp) ignoredBy(rules []config.ClipIgnoreRule) (config.ClipIgnoreRule, bool) {
+	for _, rule := range rules {
+		if rule.App == "" {
+			continue
+		}
+		if !strings.EqualFold(rule.App, src.Name) && !strings.EqualFold(rule.App, src.Instance) {
+			continue
+		}
+		if rule.Title == "" || strings.Contains(strings.ToLower(src.Title), strings.ToLower(rule.Title)) {
+			return rule, true
+		}
+	}
+	return config.ClipIgnoreRule{}, false
+}
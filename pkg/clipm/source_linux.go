@@ -0,0 +1,23 @@
+package clipm
+
+import "rilaunch/pkg/x11"
+
+// currentSource returns the focused window, which on X11 is the app the copy
+// was made in. Without X11, as on a pure Wayland session, it is unknown.
+func currentSource() (SourceApp, bool) {
+	win, err := x11.ActiveWindowInfo()
+	if err != nil {
+		return SourceApp{}, false
+	}
+	return sourceOf(win)
+}
+
+// sourceOf names the app of a window by its WM_CLASS class, or its instance
+// for windows without a class.
+func sourceOf(win x11.ActiveWindow) (SourceApp, bool) {
+	name := win.Class
+	if name == "" {
+		name = win.Instance
+	}
+	return SourceApp{Name: name, Instance: win.Instance, Title: win.Title}, name != ""
+}
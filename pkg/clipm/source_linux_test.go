@@ -0,0 +1,24 @@
+package clipm
+
+import (
+	"rilaunch/pkg/x11"
+	"testing"
+)
+
+func TestSourceOf(t *testing.T) {
+	tests := []struct {
+		win   x11.ActiveWindow
+		want  SourceApp
+		known bool
+	}{
+		{x11.ActiveWindow{Instance: "keepassxc", Class: "KeePassXC", Title: "db.kdbx"}, SourceApp{Name: "KeePassXC", Instance: "keepassxc", Title: "db.kdbx"}, true},
+		{x11.ActiveWindow{Instance: "st", Title: "~"}, SourceApp{Name: "st", Instance: "st", Title: "~"}, true},
+		{x11.ActiveWindow{Title: "untitled"}, SourceApp{Title: "untitled"}, false},
+	}
+	for _, tt := range tests {
+		got, known := sourceOf(tt.win)
+		if got != tt.want || known != tt.known {
+			t.Errorf("sourceOf(%+v) = %+v, %v; want %+v, %v", tt.win, got, known, tt.want, tt.known)
+		}
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package clipm
+
+// currentSource is only implemented for X11; elsewhere entries are recorded
+// without their source app.
+func currentSource() (SourceApp, bool) {
+	return SourceApp{}, false
+}
@@ -0,0 +1,40 @@
+package clipm
+
+import (
+	"rilaunch/pkg/config"
+	"testing"
+)
+
+func TestIgnoredBy(t *testing.T) {
+	rules := []config.ClipIgnoreRule{
+		{App: ""},
+		{App: "KeePassXC"},
+		{App: "kitty", Title: "gopass"},
+		{App: "org.gnome.Terminal", Title: "PASS "},
+	}
+	tests := []struct {
+		src  SourceApp
+		want string
+		ok   bool
+	}{
+		{SourceApp{Name: "KeePassXC", Instance: "keepassxc"}, "KeePassXC", true},
+		{SourceApp{Name: "keepassxc"}, "KeePassXC", true},
+		{SourceApp{Name: "Keepassxc-Browser", Instance: "keepassxc"}, "KeePassXC", true},
+		{SourceApp{Name: "kitty", Instance: "kitty", Title: "~/src: gopass show web"}, "kitty", true},
+		{SourceApp{Name: "kitty", Instance: "kitty", Title: "~/src: vim"}, "", false},
+		{SourceApp{Name: "Gnome-terminal", Instance: "org.gnome.Terminal", Title: "pass show bank"}, "org.gnome.Terminal", true},
+		{SourceApp{Name: "Gnome-terminal", Instance: "org.gnome.Terminal", Title: "bypass"}, "", false},
+		{SourceApp{Name: "firefox", Title: "KeePassXC - Download"}, "", false},
+		{SourceApp{Name: "KeePass"}, "", false},
+		{SourceApp{}, "", false},
+	}
+	for _, tt := range tests {
+		rule, ok := tt.src.ignoredBy(rules)
+		if ok != tt.ok || rule.App != tt.want {
+			t.Errorf("ignoredBy(%+v) = %q, %v; want %q, %v", tt.src, rule.App, ok, tt.want, tt.ok)
+		}
+	}
+	if _, ok := (SourceApp{Name: "KeePassXC"}).ignoredBy(nil); ok {
+		t.Error("ignored without rules")
+	}
+}
@@ -0,0 +1,81 @@
+package clipm
+
+import (
+	"encoding/json"
+	"fmt"
+	"rilaunch/pkg/config"
+	"strings"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Pin keeps an entry at the top of the history, and out of retention
+// pruning and ClearClipboard, until Unpin.
+func (clipm *ClipM) Pin(key string) error {
+	return clipm.modify(key, func(clipInfo *ClipInfo) error {
+		clipInfo.Pinned = true
+		return nil
+	})
+}
+
+func (clipm *ClipM) Unpin(key string) error {
+	return clipm.modify(key, func(clipInfo *ClipInfo) error {
+		clipInfo.Pinned = false
+		return nil
+	})
+}
+
+// AddTag tags an entry. Tags are compared ignoring case, and cannot contain
+// spaces, so they can be searched for with "tag:<tag>".
+func (clipm *ClipM) AddTag(key, tag string) error {
+	tag = strings.TrimSpace(tag)
+	if tag == "" || strings.ContainsAny(tag, " \t\r\n") {
+		return fmt.Errorf("invalid tag: %q", tag)
+	}
+	return clipm.modify(key, func(clipInfo *ClipInfo) error {
+		if indexTag(clipInfo.Tag, tag) < 0 {
+			clipInfo.Tag = append(clipInfo.Tag, tag)
+		}
+		return nil
+	})
+}
+
+func (clipm *ClipM) RemoveTag(key, tag string) error {
+	return clipm.modify(key, func(clipInfo *ClipInfo) error {
+		if i := indexTag(clipInfo.Tag, strings.TrimSpace(tag)); i >= 0 {
+			clipInfo.Tag = append(clipInfo.Tag[:i], clipInfo.Tag[i+1:]...)
+		}
+		return nil
+	})
+}
+
+func indexTag(tags []string, tag string) int {
+	for i, t := range tags {
+		if strings.EqualFold(t, tag) {
+			return i
+		}
+	}
+	return -1
+}
+
+// modify updates an entry in place with fn.
+func (clipm *ClipM) modify(key string, fn func(*ClipInfo) error) error {
+	return clipm.DB.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(config.ClipBucket)
+		if bucket == nil {
+			return fmt.Errorf("clipInfo not found")
+		}
+		data := bucket.Get([]byte(key))
+		if data == nil {
+			return fmt.Errorf("clipboard entry not found: %s", key)
+		}
+		var clipInfo ClipInfo
+		if err := json.Unmarshal(data, &clipInfo); err != nil {
+			return err
+		}
+		if err := fn(&clipInfo); err != nil {
+			return err
+		}
+		return putClip(tx, []byte(key), clipInfo)
+	})
+}
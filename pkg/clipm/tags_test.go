@@ -0,0 +1,77 @@
+package clipm
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTags(t *testing.T) {
+	cm := newTestClipM(t)
+	k := addClips(t, cm, 1, time.Now(), nil)[0]
+
+	for _, tag := range []string{"work", " Shell ", "WORK"} {
+		if err := cm.AddTag(k, tag); err != nil {
+			t.Fatalf("AddTag(%q): %v", tag, err)
+		}
+	}
+	for _, tag := range []string{"", "  ", "two words", "tab\tbed"} {
+		if err := cm.AddTag(k, tag); err == nil {
+			t.Errorf("AddTag(%q) succeeded, want an error", tag)
+		}
+	}
+	if err := cm.AddTag("missing", "work"); err == nil {
+		t.Error("AddTag on a missing entry succeeded")
+	}
+
+	tags := func() []string {
+		t.Helper()
+		info, err := cm.Read(k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return info.Tag
+	}
+	if got, want := tags(), []string{"work", "Shell"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("tags = %q, want %q", got, want)
+	}
+
+	if err := cm.RemoveTag(k, "shell"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cm.RemoveTag(k, "absent"); err != nil {
+		t.Errorf("RemoveTag of an absent tag: %v", err)
+	}
+	if got, want := tags(), []string{"work"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("tags after RemoveTag = %q, want %q", got, want)
+	}
+}
+
+func TestPinSurvivesClear(t *testing.T) {
+	cm := newTestClipM(t)
+	k := addClips(t, cm, 3, time.Now(), nil)
+
+	if err := cm.Pin(k[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := cm.Pin(k[1]); err != nil {
+		t.Fatal(err)
+	}
+	if err := cm.Unpin(k[1]); err != nil {
+		t.Fatal(err)
+	}
+	if err := cm.Pin("missing"); err == nil {
+		t.Error("Pin on a missing entry succeeded")
+	}
+
+	if err := cm.DeleteBucket(); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := queryAll(t, cm, ClipQuery{})
+	if want := []string{k[0]}; !reflect.DeepEqual(got, want) {
+		t.Errorf("entries after clearing = %v, want only the pinned %v", got, want)
+	}
+	if info, err := cm.Read(k[0]); err != nil || !info.Pinned {
+		t.Errorf("pinned entry after clearing = %+v, %v", info, err)
+	}
+}
@@ -18,6 +18,12 @@ import (
 )
 
 var ClipBucket = []byte("Clipboard")
+var ClipImageBucket = []byte("ClipboardImages")
+var ClipThumbBucket = []byte("ClipboardThumbnails")
+
+// ClipIndexBucket orders the clipboard entries by timestamp, see clipm.Query.
+var ClipIndexBucket = []byte("ClipboardByTime")
+var LaunchBucket = []byte("LaunchHistory")
 
 type Config struct {
 	DB *bolt.DB
@@ -36,13 +42,21 @@ func GetInstance() *Config {
 			log.Fatal(errors.Wrap(err, "Failed to get the default config directory"))
 		}
 
-		db, err := bolt.Open(path.Join(dir, "palcb.db"), 0600, &bolt.Options{Timeout: 1 * time.Second})
+		dbPath := path.Join(dir, "palcb.db")
+		if err := compactIfWasteful(dbPath); err != nil {
+			fmt.Printf("Warning: failed to compact %s: %v\n", dbPath, err)
+		}
+		db, err := bolt.Open(dbPath, 0600, &bolt.Options{Timeout: 1 * time.Second})
 		if err != nil {
 			log.Fatal("DB Open", err)
 		}
 		err = db.Update(func(tx *bolt.Tx) error {
-			_, err := tx.CreateBucketIfNotExists(ClipBucket)
-			return err
+			for _, bucket := range [][]byte{ClipBucket, ClipImageBucket, ClipThumbBucket, ClipIndexBucket, LaunchBucket} {
+				if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+					return err
+				}
+			}
+			return nil
 		})
 		if err != nil {
 			log.Fatal("DB Update", err)
@@ -54,6 +68,96 @@ func GetInstance() *Config {
 	return instance
 }
 
+// A bolt file never shrinks: pages freed by deletions are only reused. Once
+// the file is larger than compactMinBytes and mostly free pages, it is
+// rewritten at startup, before anything else has it open.
+const (
+	compactMinBytes  = 64 << 20
+	compactFreeRatio = 0.5
+)
+
+// RequestCompaction makes the next startup compact the database regardless
+// of its size, e.g. to drop freed pages that still hold plaintext secrets.
+func RequestCompaction() error {
+	dir, err := GetDefaultConfigDir()
+	if err != nil {
+		return err
+	}
+	return Touch(path.Join(dir, "palcb.db.compact"))
+}
+
+func compactIfWasteful(dbPath string) error {
+	marker := dbPath + ".compact"
+	_, err := os.Stat(marker)
+	requested := err == nil
+
+	info, err := os.Stat(dbPath)
+	if err != nil || info.Size() < compactMinBytes && !requested {
+		return nil
+	}
+
+	src, err := bolt.Open(dbPath, 0600, &bolt.Options{Timeout: 1 * time.Second, ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	tmpPath := dbPath + ".tmp"
+	compacted, err := compactInto(src, tmpPath, info.Size(), requested)
+	// The source must be closed before it is replaced.
+	if closeErr := src.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		if compacted {
+			os.Remove(tmpPath)
+		}
+		return err
+	}
+	if !compacted {
+		return nil
+	}
+	if err := os.Rename(tmpPath, dbPath); err != nil {
+		return err
+	}
+	os.Remove(marker)
+	return nil
+}
+
+// compactInto copies src to a new database at tmpPath, when requested or
+// when at least compactFreeRatio of its size is free pages. It reports
+// whether it wrote the copy.
+func compactInto(src *bolt.DB, tmpPath string, size int64, requested bool) (bool, error) {
+	var used int64
+	err := src.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+			stats := b.Stats()
+			used += int64(stats.BranchAlloc + stats.LeafAlloc)
+			return nil
+		})
+	})
+	if err != nil {
+		return false, err
+	}
+	if !requested && float64(used) > float64(size)*(1-compactFreeRatio) {
+		return false, nil
+	}
+
+	os.Remove(tmpPath)
+	dst, err := bolt.Open(tmpPath, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		return false, err
+	}
+	if err := bolt.Compact(dst, src, 64<<20); err != nil {
+		dst.Close()
+		os.Remove(tmpPath)
+		return false, err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(tmpPath)
+		return false, err
+	}
+	return true, nil
+}
+
 func GetUserConfigDir() (dir string, err error) {
 	if runtime.GOOS == "windows" {
 		dir = os.Getenv("APPDATA")
@@ -86,7 +190,8 @@ func GetDefaultConfigDir() (dir string, err error) {
 	return dir, nil
 }
 
-func expandPath(s string) string {
+// ExpandPath expands a leading "~/" and environment variables in a path.
+func ExpandPath(s string) string {
 	if len(s) >= 2 && s[0] == '~' && os.IsPathSeparator(s[1]) {
 		if runtime.GOOS == "windows" {
 			s = filepath.Join(os.Getenv("USERPROFILE"), s[2:])
@@ -121,9 +226,45 @@ func Touch(fileName string) error {
 // Settings holds user-configurable app preferences, persisted to settings.json.
 type Settings struct {
 	NotesDir string `json:"notesDir"`
+	// AppImageDirs are scanned for *.AppImage files, e.g. "~/Applications".
+	AppImageDirs []string `json:"appImageDirs"`
+	// ScanProgramFiles adds every .exe under Program Files to the app list on
+	// Windows, in addition to the Start Menu shortcuts.
+	ScanProgramFiles bool `json:"scanProgramFiles"`
+	// FocusRunningApps raises the window of an app that is already running
+	// instead of starting another instance. Needs X11; elsewhere apps are
+	// always launched.
+	FocusRunningApps bool `json:"focusRunningApps"`
+	// IconTheme overrides the freedesktop icon theme used for app icons.
+	IconTheme string `json:"iconTheme"`
+	// TerminalCommand runs Terminal=true apps, e.g. "kitty -e". When empty,
+	// $TERMINAL or the first installed known terminal is used.
+	TerminalCommand string `json:"terminalCommand"`
+	// Clipboard retention: the oldest entries beyond ClipMaxEntries, older
+	// than ClipMaxAgeDays, or beyond ClipMaxBytes of content in total are
+	// pruned. Pinned entries are kept, and do not count towards the limits.
+	// 0 disables a limit; all are off until the user sets them.
+	ClipMaxEntries int   `json:"clipMaxEntries"`
+	ClipMaxAgeDays int   `json:"clipMaxAgeDays"`
+	ClipMaxBytes   int64 `json:"clipMaxBytes"`
+	// ClipSecretAction is what happens to copied text that looks like a
+	// password, key or token: "mark" stores it encrypted as a secret entry,
+	// "skip" does not store it, and "off" disables detection.
+	ClipSecretAction string `json:"clipSecretAction"`
+	// ClipIgnoreApps lists the apps whose copies are never recorded.
+	ClipIgnoreApps []ClipIgnoreRule `json:"clipIgnoreApps"`
+}
+
+// ClipIgnoreRule matches copies made in an app, by its WM_CLASS instance or
+// class, ignoring case. With a Title, only windows whose title contains it
+// match, e.g. a terminal running pass or gopass.
+type ClipIgnoreRule struct {
+	App   string `json:"app"`
+	Title string `json:"title,omitempty"`
 }
 
-func settingsFilePath() string {
+// SettingsFilePath returns the path of settings.json in the config dir.
+func SettingsFilePath() string {
 	dir, _ := GetDefaultConfigDir()
 	return filepath.Join(dir, "settings.json")
 }
@@ -132,9 +273,15 @@ func settingsFilePath() string {
 func LoadSettings() *Settings {
 	dir, _ := GetDefaultConfigDir()
 	s := &Settings{
-		NotesDir: filepath.Join(dir, "notes"),
+		NotesDir:         filepath.Join(dir, "notes"),
+		ClipSecretAction: "mark",
+		ClipIgnoreApps: []ClipIgnoreRule{
+			{App: "KeePassXC"},
+			{App: "1Password"},
+			{App: "Bitwarden"},
+		},
 	}
-	data, err := os.ReadFile(settingsFilePath())
+	data, err := os.ReadFile(SettingsFilePath())
 	if err != nil {
 		return s
 	}
@@ -147,11 +294,39 @@ func LoadSettings() *Settings {
 	return s
 }
 
+// The settings CurrentSettings last read, and the file they were read from.
+var (
+	settingsMu    sync.Mutex
+	settingsCache *Settings
+	settingsPath  string
+	settingsMod   time.Time
+	settingsSize  int64
+)
+
+// CurrentSettings returns the settings like LoadSettings, but reads
+// settings.json again only when it has changed, for callers that run on
+// every copy or launch. The result is shared and must not be modified.
+func CurrentSettings() *Settings {
+	path := SettingsFilePath()
+	var mod time.Time
+	size := int64(-1)
+	if info, err := os.Stat(path); err == nil {
+		mod, size = info.ModTime(), info.Size()
+	}
+
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	if settingsCache == nil || path != settingsPath || !mod.Equal(settingsMod) || size != settingsSize {
+		settingsCache, settingsPath, settingsMod, settingsSize = LoadSettings(), path, mod, size
+	}
+	return settingsCache
+}
+
 // SaveSettings persists settings to settings.json.
 func SaveSettings(s *Settings) error {
 	data, err := json.MarshalIndent(s, "", "  ")
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(settingsFilePath(), data, 0o600)
+	return os.WriteFile(SettingsFilePath(), data, 0o600)
 }
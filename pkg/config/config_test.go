@@ -0,0 +1,128 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestCurrentSettings(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("PAL_CONFIG_DIR", dir)
+
+	if s := CurrentSettings(); len(s.ClipIgnoreApps) != 3 || s.ClipSecretAction != "mark" {
+		t.Errorf("defaults = %+v", s)
+	}
+	if a, b := CurrentSettings(), CurrentSettings(); a != b {
+		t.Error("unchanged settings were read again")
+	}
+
+	path := filepath.Join(dir, "settings.json")
+	if err := os.WriteFile(path, []byte(`{"clipIgnoreApps": [{"app": "kitty"}]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if s := CurrentSettings(); len(s.ClipIgnoreApps) != 1 || s.ClipIgnoreApps[0].App != "kitty" {
+		t.Errorf("after writing settings.json: %+v", s.ClipIgnoreApps)
+	}
+
+	// Same size, different content and time.
+	if err := os.WriteFile(path, []byte(`{"clipIgnoreApps": [{"app": "kiwi!"}]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Second)
+	os.Chtimes(path, later, later)
+	if s := CurrentSettings(); s.ClipIgnoreApps[0].App != "kiwi!" {
+		t.Errorf("after editing settings.json: %+v", s.ClipIgnoreApps)
+	}
+
+	if err := SaveSettings(&Settings{ClipSecretAction: "skip"}); err != nil {
+		t.Fatal(err)
+	}
+	if s := CurrentSettings(); s.ClipSecretAction != "skip" {
+		t.Errorf("after SaveSettings: %+v", s)
+	}
+}
+
+func TestCompactIfWasteful(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "palcb.db")
+	db, err := bolt.Open(dbPath, 0o600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucket(ClipBucket)
+		if err != nil {
+			return err
+		}
+		for i := range 2000 {
+			if err := b.Put([]byte(fmt.Sprintf("%05d", i)), make([]byte, 1024)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		err = db.Update(func(tx *bolt.Tx) error {
+			b := tx.Bucket(ClipBucket)
+			for i := 1; i < 2000; i++ {
+				if err := b.Delete([]byte(fmt.Sprintf("%05d", i))); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	if closeErr := db.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	size := func() int64 {
+		info, err := os.Stat(dbPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return info.Size()
+	}
+	before := size()
+
+	// Small databases are left alone unless compaction was requested.
+	if err := compactIfWasteful(dbPath); err != nil {
+		t.Fatal(err)
+	}
+	if size() != before {
+		t.Error("small database compacted without a request")
+	}
+
+	if err := Touch(dbPath + ".compact"); err != nil {
+		t.Fatal(err)
+	}
+	if err := compactIfWasteful(dbPath); err != nil {
+		t.Fatal(err)
+	}
+	if after := size(); after >= before {
+		t.Errorf("size after compaction = %d, want less than %d", after, before)
+	}
+	for _, leftover := range []string{dbPath + ".compact", dbPath + ".tmp"} {
+		if _, err := os.Stat(leftover); err == nil {
+			t.Errorf("%s left behind", filepath.Base(leftover))
+		}
+	}
+
+	db, err = bolt.Open(dbPath, 0o600, &bolt.Options{ReadOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.View(func(tx *bolt.Tx) error {
+		if v := tx.Bucket(ClipBucket).Get([]byte("00000")); len(v) != 1024 {
+			t.Errorf("kept entry = %d bytes after compaction, want 1024", len(v))
+		}
+		return nil
+	})
+}
@@ -0,0 +1,270 @@
+// Package fuzzy scores how well a search pattern matches a piece of text,
+// the way launcher searches are typed: "vsc" for "Visual Studio Code" or
+// "gmp" for "GIMP".
+package fuzzy
+
+import (
+	"sync"
+	"unicode"
+)
+
+// Range is a run of matched characters, as rune offsets [Start, End).
+type Range struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// Match is the result of scoring a pattern against a text.
+type Match struct {
+	Score  int     `json:"score"`
+	Ranges []Range `json:"ranges"`
+}
+
+const (
+	scoreMatch       = 16
+	bonusBoundary    = 24
+	bonusFirstChar   = 8
+	bonusCamelCase   = 20
+	bonusConsecutive = 12
+	penaltyGap       = 2
+	penaltyLeading   = 1
+	maxLeadingGap    = 10
+
+	// MaxTextLength bounds the number of runes scored, so long clipboard
+	// entries stay cheap to search. Characters beyond it never match.
+	MaxTextLength = 4096
+
+	// maxAlignLength bounds the texts scored as a subsequence, which costs
+	// time and memory in proportion to pattern × text length. Longer texts,
+	// such as clipboard entries, only match where they contain the pattern.
+	maxAlignLength = 256
+)
+
+// alignBuf holds the tables of an alignment, reused across calls so
+// searching many texts does not allocate them each time.
+type alignBuf struct {
+	lower []rune
+	bonus []int
+	score []int
+	prev  []int32
+}
+
+var alignPool = sync.Pool{New: func() any { return new(alignBuf) }}
+
+// grow returns s resized to n elements, reusing its storage when it can.
+func grow[T any](s []T, n int) []T {
+	if cap(s) < n {
+		return make([]T, n)
+	}
+	return s[:n]
+}
+
+// Score matches pattern against text as a case-insensitive subsequence and
+// returns the best scoring alignment. Matches at word starts, camelCase humps
+// and runs of consecutive characters score higher, so acronyms and prefixes
+// rank above scattered matches. Spaces in the pattern are ignored. Texts
+// longer than maxAlignLength runes only match where they contain the pattern.
+func Score(pattern, text string) (Match, bool) {
+	var pat []rune
+	for _, r := range pattern {
+		if !unicode.IsSpace(r) {
+			pat = append(pat, unicode.ToLower(r))
+		}
+	}
+	if len(pat) == 0 {
+		return Match{}, true
+	}
+
+	txt := []rune(text)
+	if len(txt) > MaxTextLength {
+		txt = txt[:MaxTextLength]
+	}
+	if len(pat) > len(txt) {
+		return Match{}, false
+	}
+
+	if len(txt) > maxAlignLength {
+		return scoreSubstring(pat, txt)
+	}
+	return align(pat, txt)
+}
+
+// align finds the best scoring alignment of pat as a subsequence of txt.
+func align(pat, txt []rune) (Match, bool) {
+	buf := alignPool.Get().(*alignBuf)
+	defer alignPool.Put(buf)
+
+	n, m := len(pat), len(txt)
+	lower := grow(buf.lower, m)
+	for i, r := range txt {
+		lower[i] = unicode.ToLower(r)
+	}
+	buf.lower = lower
+	if !isSubsequence(pat, lower) {
+		return Match{}, false
+	}
+
+	bonus := grow(buf.bonus, m)
+	for j := range txt {
+		bonus[j] = charBonus(txt, j)
+	}
+	buf.bonus = bonus
+
+	// score[i*m+j] is the best score with pat[i] matched at txt[j]; prev
+	// holds the position pat[i-1] was matched at for that alignment.
+	const none = -1 << 30
+	score := grow(buf.score, n*m)
+	prev := grow(buf.prev, n*m)
+	buf.score, buf.prev = score, prev
+
+	for j := 0; j < m; j++ {
+		score[j] = none
+		if lower[j] == pat[0] {
+			leading := min(j, maxLeadingGap)
+			score[j] = scoreMatch + bonus[j] - leading*penaltyLeading
+			if j == 0 {
+				score[j] += bonusFirstChar
+			}
+		}
+	}
+
+	for i := 1; i < n; i++ {
+		row, above := score[i*m:(i+1)*m], score[(i-1)*m:i*m]
+		// best tracks max(above[k] + k*penaltyGap) over k < j-1, so a
+		// gapped predecessor costs (j-k-1)*penaltyGap.
+		best, bestK := none, -1
+		for j := 0; j < m; j++ {
+			row[j] = none
+			if j >= 2 && above[j-2] != none {
+				if v := above[j-2] + (j-2)*penaltyGap; v > best {
+					best, bestK = v, j-2
+				}
+			}
+			if lower[j] != pat[i] || j < i {
+				continue
+			}
+
+			s, from := none, -1
+			if bestK >= 0 {
+				s, from = best-(j-1)*penaltyGap, bestK
+			}
+			if above[j-1] != none {
+				if v := above[j-1] + bonusConsecutive; v >= s {
+					s, from = v, j-1
+				}
+			}
+			if from < 0 {
+				continue
+			}
+			row[j] = s + scoreMatch + bonus[j]
+			prev[i*m+j] = int32(from)
+		}
+	}
+
+	end, total := -1, none
+	last := score[(n-1)*m:]
+	for j := 0; j < m; j++ {
+		if last[j] > total {
+			end, total = j, last[j]
+		}
+	}
+	if end < 0 {
+		return Match{}, false
+	}
+
+	positions := make([]int, n)
+	for i, j := n-1, end; i >= 0; i-- {
+		positions[i] = j
+		j = int(prev[i*m+j])
+	}
+	return Match{Score: total, Ranges: toRanges(positions)}, true
+}
+
+// scoreSubstring finds the best scoring occurrence of pat in txt, scored
+// as align would score a run of consecutive characters.
+func scoreSubstring(pat, txt []rune) (Match, bool) {
+	n := len(pat)
+	start, total := -1, 0
+	for s := 0; s+n <= len(txt); s++ {
+		k := 0
+		for k < n && unicode.ToLower(txt[s+k]) == pat[k] {
+			k++
+		}
+		if k < n {
+			continue
+		}
+
+		v := n*scoreMatch + (n-1)*bonusConsecutive - min(s, maxLeadingGap)*penaltyLeading
+		if s == 0 {
+			v += bonusFirstChar
+		}
+		for j := s; j < s+n; j++ {
+			v += charBonus(txt, j)
+		}
+		if start < 0 || v > total {
+			start, total = s, v
+		}
+	}
+	if start < 0 {
+		return Match{}, false
+	}
+	return Match{Score: total, Ranges: []Range{{Start: start, End: start + n}}}, true
+}
+
+// Best returns the best match of pattern against any of texts, along with
+// the index of the text it was found in.
+func Best(pattern string, texts ...string) (Match, int, bool) {
+	var best Match
+	index, found := -1, false
+	for i, text := range texts {
+		if m, ok := Score(pattern, text); ok && (!found || m.Score > best.Score) {
+			best, index, found = m, i, true
+		}
+	}
+	return best, index, found
+}
+
+func isSubsequence(pat, text []rune) bool {
+	i := 0
+	for _, r := range text {
+		if i < len(pat) && r == pat[i] {
+			i++
+		}
+	}
+	return i == len(pat)
+}
+
+// charBonus rewards characters that start a word: the first character, one
+// following a separator, an upper-case letter following a lower-case one
+// (camelCase), and a digit following a letter.
+func charBonus(text []rune, j int) int {
+	if j == 0 {
+		return bonusBoundary
+	}
+	prev, cur := text[j-1], text[j]
+	switch {
+	case isSeparator(prev) && !isSeparator(cur):
+		return bonusBoundary
+	case unicode.IsLower(prev) && unicode.IsUpper(cur):
+		return bonusCamelCase
+	case unicode.IsLetter(prev) != unicode.IsLetter(cur) && unicode.IsDigit(cur):
+		return bonusCamelCase
+	}
+	return 0
+}
+
+func isSeparator(r rune) bool {
+	return unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r)
+}
+
+func toRanges(positions []int) []Range {
+	var ranges []Range
+	for _, p := range positions {
+		if n := len(ranges); n > 0 && ranges[n-1].End == p {
+			ranges[n-1].End++
+			continue
+		}
+		ranges = append(ranges, Range{Start: p, End: p + 1})
+	}
+	return ranges
+}
@@ -0,0 +1,131 @@
+package fuzzy
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestScoreRanges(t *testing.T) {
+	tests := []struct {
+		pattern, text string
+		want          []Range
+	}{
+		{"vsc", "Visual Studio Code", []Range{{0, 1}, {7, 8}, {14, 15}}},
+		{"gmp", "GIMP", []Range{{0, 1}, {2, 4}}},
+		{"GIMP", "gimp", []Range{{0, 4}}},
+		{"code", "Visual Studio Code", []Range{{14, 18}}},
+		{"wg", "dnsWaitGroup", []Range{{3, 4}, {7, 8}}},
+		{"v s c", "Visual Studio Code", []Range{{0, 1}, {7, 8}, {14, 15}}},
+		{"fire", "Firefox Web Browser", []Range{{0, 4}}},
+		{"gt", "gnome-terminal", []Range{{0, 1}, {6, 7}}},
+		{"é", "Café", []Range{{3, 4}}},
+	}
+	for _, tt := range tests {
+		m, ok := Score(tt.pattern, tt.text)
+		if !ok {
+			t.Errorf("Score(%q, %q): no match", tt.pattern, tt.text)
+			continue
+		}
+		if !reflect.DeepEqual(m.Ranges, tt.want) {
+			t.Errorf("Score(%q, %q).Ranges = %v, want %v", tt.pattern, tt.text, m.Ranges, tt.want)
+		}
+	}
+}
+
+func TestScoreNoMatch(t *testing.T) {
+	tests := []struct{ pattern, text string }{
+		{"xyz", "Visual Studio Code"},
+		{"cvs", "Visual Studio Code"},
+		{"gimpp", "GIMP"},
+		{"a", ""},
+	}
+	for _, tt := range tests {
+		if m, ok := Score(tt.pattern, tt.text); ok {
+			t.Errorf("Score(%q, %q) = %+v, want no match", tt.pattern, tt.text, m)
+		}
+	}
+	if _, ok := Score(" ", "anything"); !ok {
+		t.Error("an empty pattern should match")
+	}
+}
+
+// TestScoreOrder checks that word starts, camelCase humps and consecutive
+// characters score above scattered matches.
+func TestScoreOrder(t *testing.T) {
+	tests := []struct {
+		pattern, better, worse string
+	}{
+		{"vsc", "Visual Studio Code", "Visual Basic Script Compiler Converter"},
+		{"vsc", "Visual Studio Code", "devscripts"},
+		{"gmp", "GIMP", "Grand Champion"},
+		{"gmp", "Gnome Maps Preview", "GIMP"},
+		{"code", "Code", "Visual Studio Code"},
+		{"code", "Visual Studio Code", "Decoder"},
+		{"term", "Terminal", "Alacritty Terminal"},
+		{"term", "Alacritty Terminal", "Patterm"},
+		{"wg", "dnsWaitGroup", "dnswaitgroup"},
+		{"fi", "Files", "Profile"},
+		{"calc", "libreoffice-calc", "localcache"},
+	}
+	for _, tt := range tests {
+		b, okB := Score(tt.pattern, tt.better)
+		w, okW := Score(tt.pattern, tt.worse)
+		if !okB || !okW {
+			t.Errorf("%q: expected both %q and %q to match", tt.pattern, tt.better, tt.worse)
+			continue
+		}
+		if b.Score <= w.Score {
+			t.Errorf("%q: %q scored %d, not above %q with %d", tt.pattern, tt.better, b.Score, tt.worse, w.Score)
+		}
+	}
+}
+
+func TestScoreLongText(t *testing.T) {
+	text := strings.Repeat("lorem ipsum dolor ", 20) + "needle in a haystack"
+
+	m, ok := Score("needle", text)
+	if !ok {
+		t.Fatal("substring of a long text did not match")
+	}
+	start := len(text) - len("needle in a haystack")
+	if want := []Range{{start, start + 6}}; !reflect.DeepEqual(m.Ranges, want) {
+		t.Errorf("Ranges = %v, want %v", m.Ranges, want)
+	}
+
+	// Scattered matches are not looked for in long texts.
+	if _, ok := Score("nih", text); ok {
+		t.Error("subsequence of a long text matched")
+	}
+
+	// A substring scores the same whether or not the text is long.
+	short, _ := Score("needle", "needle in a haystack")
+	long, _ := Score("needle", "needle in a haystack"+strings.Repeat(" lorem ipsum", 30))
+	if short.Score != long.Score {
+		t.Errorf("long text scored %d, short text %d", long.Score, short.Score)
+	}
+
+	// Characters beyond MaxTextLength never match.
+	if _, ok := Score("needle", strings.Repeat("x", MaxTextLength)+"needle"); ok {
+		t.Error("matched beyond MaxTextLength")
+	}
+}
+
+func TestBest(t *testing.T) {
+	m, i, ok := Best("vsc", "devscripts", "Visual Studio Code", "xyz")
+	if !ok || i != 1 {
+		t.Fatalf("Best = %+v, %d, %v; want index 1", m, i, ok)
+	}
+	if _, _, ok := Best("vsc", "xyz", ""); ok {
+		t.Error("Best matched no text")
+	}
+}
+
+func BenchmarkScore(b *testing.B) {
+	texts := []string{"Visual Studio Code", "GNU Image Manipulation Program", strings.Repeat("lorem ipsum ", 300)}
+	for b.Loop() {
+		for _, text := range texts {
+			Score("vsc", text)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package notes
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"rilaunch/pkg/fuzzy"
+)
+
+// maxTitleLength is the number of runes of a note's first line shown in
+// the notes list.
+const maxTitleLength = 72
+
+var (
+	headingPrefix = regexp.MustCompile(`^#+\s*`)
+	emphasisMarks = strings.NewReplacer("*", "", "_", "", "`", "")
+)
+
+// NoteMatch is a note found by Search. Matches are rune ranges in Title,
+// empty when the query only matched the rest of the note.
+type NoteMatch struct {
+	Note
+	Title   string        `json:"title"`
+	Score   int           `json:"score"`
+	Matches []fuzzy.Range `json:"matches,omitempty"`
+}
+
+// Title returns the line a note is listed under: its first non-empty line
+// without heading and emphasis marks, cut to maxTitleLength runes.
+func Title(content string) string {
+	for line := range strings.SplitSeq(content, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		title := emphasisMarks.Replace(headingPrefix.ReplaceAllString(line, ""))
+		if r := []rune(title); len(r) > maxTitleLength {
+			title = string(r[:maxTitleLength])
+		}
+		return title
+	}
+	return ""
+}
+
+// Search returns the notes that fuzzy-match query, best matches first and
+// in their given order among equal matches. A match in the title counts as
+// well as one in the body, but only title matches carry ranges.
+func Search(notes []Note, query string) []NoteMatch {
+	matches := []NoteMatch{}
+	for _, n := range notes {
+		title := Title(n.Content)
+		if strings.TrimSpace(query) == "" {
+			matches = append(matches, NoteMatch{Note: n, Title: title})
+			continue
+		}
+		m, i, ok := fuzzy.Best(query, title, n.Content)
+		if !ok {
+			continue
+		}
+		match := NoteMatch{Note: n, Title: title, Score: m.Score}
+		if i == 0 {
+			match.Matches = m.Ranges
+		}
+		matches = append(matches, match)
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+	return matches
+}
+
+// Search returns the stored notes matching query, newest first among equal
+// matches.
+func (s *NotesStore) Search(query string) ([]NoteMatch, error) {
+	notes, err := s.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	return Search(notes, query), nil
+}
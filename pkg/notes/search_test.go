@@ -0,0 +1,64 @@
+package notes
+
+import (
+	"reflect"
+	"testing"
+
+	"rilaunch/pkg/fuzzy"
+)
+
+func TestTitle(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{"", ""},
+		{"plain line\nsecond", "plain line"},
+		{"\n  \n## Heading\nbody", "Heading"},
+		{"**bold** and `code` and _em_", "bold and code and em"},
+		{string(make([]rune, 100)), string(make([]rune, maxTitleLength))},
+	}
+	for _, tt := range tests {
+		if got := Title(tt.content); got != tt.want {
+			t.Errorf("Title(%q) = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestSearch(t *testing.T) {
+	notes := []Note{
+		{ID: "newest", Content: "# Groceries\nmilk, eggs"},
+		{ID: "middle", Content: "Meeting notes\ndiscuss the groceries budget"},
+		{ID: "oldest", Content: "Unrelated"},
+	}
+
+	ids := func(matches []NoteMatch) []string {
+		var ids []string
+		for _, m := range matches {
+			ids = append(ids, m.ID)
+		}
+		return ids
+	}
+
+	if got := ids(Search(notes, "")); !reflect.DeepEqual(got, []string{"newest", "middle", "oldest"}) {
+		t.Errorf("empty query = %v, want all notes in order", got)
+	}
+
+	got := Search(notes, "groc")
+	if want := []string{"newest", "middle"}; !reflect.DeepEqual(ids(got), want) {
+		t.Fatalf("Search(groc) = %v, want %v", ids(got), want)
+	}
+	if want := []fuzzy.Range{{Start: 0, End: 4}}; !reflect.DeepEqual(got[0].Matches, want) {
+		t.Errorf("title match ranges = %v, want %v", got[0].Matches, want)
+	}
+	if got[0].Title != "Groceries" {
+		t.Errorf("title = %q, want Groceries", got[0].Title)
+	}
+	if got[1].Matches != nil {
+		t.Errorf("body match ranges = %v, want none", got[1].Matches)
+	}
+
+	if got := Search(notes, "zzz"); len(got) != 0 {
+		t.Errorf("Search(zzz) = %v, want none", ids(got))
+	}
+}
@@ -0,0 +1,404 @@
+// Package x11 is a minimal X11 client, just enough to find the window of a
+// process, ask the window manager to activate it and tell which window is
+// active, see the Extended Window Manager Hints:
+// https://specifications.freedesktop.org/wm-spec/latest/
+package x11
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNoWindow is returned when no window matches.
+var ErrNoWindow = errors.New("no window found")
+
+const (
+	x11InternAtom    = 16
+	x11GetProperty   = 20
+	x11SendEvent     = 25
+	x11GetInputFocus = 43
+
+	x11ClientMessage = 33
+
+	x11SubstructureNotifyMask   = 1 << 19
+	x11SubstructureRedirectMask = 1 << 20
+
+	x11Timeout = 2 * time.Second
+)
+
+type x11Conn struct {
+	conn net.Conn
+	root uint32
+	// atoms caches interned atoms by name.
+	atoms map[string]uint32
+}
+
+// FocusWindow activates the topmost window owned by one of pids, or whose
+// WM_CLASS instance or class is one of classes.
+func FocusWindow(pids []int, classes []string) error {
+	if runtime.GOOS != "linux" {
+		return fmt.Errorf("focusing windows is not supported on %s", runtime.GOOS)
+	}
+	x, err := dialX11()
+	if err != nil {
+		return err
+	}
+	defer x.conn.Close()
+
+	win, err := x.findWindow(pids, classes)
+	if err != nil {
+		return err
+	}
+	return x.activate(win)
+}
+
+// dialX11 connects to the local display named by $DISPLAY, authenticating
+// with the MIT-MAGIC-COOKIE-1 from the Xauthority file when there is one.
+func dialX11() (*x11Conn, error) {
+	display := os.Getenv("DISPLAY")
+	host, rest, ok := strings.Cut(display, ":")
+	if !ok || (host != "" && host != "unix") {
+		return nil, fmt.Errorf("unsupported X display %q", display)
+	}
+	number, _, _ := strings.Cut(rest, ".")
+
+	conn, err := net.DialTimeout("unix", "/tmp/.X11-unix/X"+number, x11Timeout)
+	if err != nil {
+		return nil, err
+	}
+	// Requests set their own deadline; this one covers the handshake.
+	conn.SetDeadline(time.Now().Add(x11Timeout))
+
+	x := &x11Conn{conn: conn, atoms: make(map[string]uint32)}
+	if err := x.setup(xauthCookie(number)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return x, nil
+}
+
+func (x *x11Conn) setup(cookie []byte) error {
+	var authName []byte
+	if cookie != nil {
+		authName = []byte("MIT-MAGIC-COOKIE-1")
+	}
+
+	var req bytes.Buffer
+	req.Write([]byte{'l', 0})
+	binary.Write(&req, binary.LittleEndian, []uint16{11, 0, uint16(len(authName)), uint16(len(cookie)), 0})
+	req.Write(pad4(authName))
+	req.Write(pad4(cookie))
+	if _, err := x.conn.Write(req.Bytes()); err != nil {
+		return err
+	}
+
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(x.conn, header); err != nil {
+		return err
+	}
+	body := make([]byte, int(binary.LittleEndian.Uint16(header[6:]))*4)
+	if _, err := io.ReadFull(x.conn, body); err != nil {
+		return err
+	}
+	if header[0] != 1 {
+		return fmt.Errorf("X server refused connection")
+	}
+
+	if len(body) < 32 {
+		return fmt.Errorf("short X setup reply")
+	}
+	vendorLen := int(binary.LittleEndian.Uint16(body[16:]))
+	formats := int(body[21])
+	screen := 32 + (vendorLen+3)&^3 + formats*8
+	if screen+4 > len(body) {
+		return fmt.Errorf("short X setup reply")
+	}
+	x.root = binary.LittleEndian.Uint32(body[screen:])
+	return nil
+}
+
+func pad4(b []byte) []byte {
+	return append(b, make([]byte, (4-len(b)%4)%4)...)
+}
+
+// xauthCookie returns the MIT-MAGIC-COOKIE-1 for the local display number
+// from $XAUTHORITY or ~/.Xauthority.
+func xauthCookie(number string) []byte {
+	path := os.Getenv("XAUTHORITY")
+	if path == "" {
+		path = filepath.Join(os.Getenv("HOME"), ".Xauthority")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	hostname, _ := os.Hostname()
+
+	const familyLocal, familyWild = 256, 65535
+	for len(data) >= 2 {
+		family := binary.BigEndian.Uint16(data)
+		data = data[2:]
+		var fields [4][]byte
+		for i := range fields {
+			if len(data) < 2 {
+				return nil
+			}
+			n := int(binary.BigEndian.Uint16(data))
+			if len(data) < 2+n {
+				return nil
+			}
+			fields[i], data = data[2:2+n], data[2+n:]
+		}
+		address, display, name, cookie := fields[0], fields[1], fields[2], fields[3]
+		if string(name) != "MIT-MAGIC-COOKIE-1" || (len(display) > 0 && string(display) != number) {
+			continue
+		}
+		if family == familyWild || family == familyLocal && string(address) == hostname {
+			return cookie
+		}
+	}
+	return nil
+}
+
+// request sends a request and, if wantReply, returns the reply. Events are
+// skipped and X errors returned. Each request gets x11Timeout to complete.
+func (x *x11Conn) request(opcode, data byte, body []byte, wantReply bool) ([]byte, error) {
+	x.conn.SetDeadline(time.Now().Add(x11Timeout))
+	req := make([]byte, 4, 4+len(body))
+	req[0], req[1] = opcode, data
+	binary.LittleEndian.PutUint16(req[2:], uint16((4+len(body))/4))
+	req = append(req, body...)
+	if _, err := x.conn.Write(req); err != nil {
+		return nil, err
+	}
+	if !wantReply {
+		return nil, nil
+	}
+
+	for {
+		reply := make([]byte, 32)
+		if _, err := io.ReadFull(x.conn, reply); err != nil {
+			return nil, err
+		}
+		switch reply[0] {
+		case 0:
+			return nil, fmt.Errorf("X error %d for request %d", reply[1], opcode)
+		case 1:
+			extra := make([]byte, int(binary.LittleEndian.Uint32(reply[4:]))*4)
+			if _, err := io.ReadFull(x.conn, extra); err != nil {
+				return nil, err
+			}
+			return append(reply, extra...), nil
+		}
+	}
+}
+
+func (x *x11Conn) atom(name string) (uint32, error) {
+	if a, ok := x.atoms[name]; ok {
+		return a, nil
+	}
+	body := make([]byte, 4)
+	binary.LittleEndian.PutUint16(body, uint16(len(name)))
+	body = append(body, pad4([]byte(name))...)
+	reply, err := x.request(x11InternAtom, 1, body, true)
+	if err != nil {
+		return 0, err
+	}
+	a := binary.LittleEndian.Uint32(reply[8:])
+	x.atoms[name] = a
+	return a, nil
+}
+
+// property returns the value of a window property of any type, or nil if
+// the window does not have it.
+func (x *x11Conn) property(win, prop uint32) ([]byte, error) {
+	body := make([]byte, 20)
+	binary.LittleEndian.PutUint32(body[0:], win)
+	binary.LittleEndian.PutUint32(body[4:], prop)
+	binary.LittleEndian.PutUint32(body[16:], 1<<16)
+	reply, err := x.request(x11GetProperty, 0, body, true)
+	if err != nil {
+		return nil, err
+	}
+	format := int(reply[1])
+	n := int(binary.LittleEndian.Uint32(reply[16:])) * format / 8
+	if 32+n > len(reply) {
+		return nil, fmt.Errorf("short GetProperty reply")
+	}
+	return reply[32 : 32+n], nil
+}
+
+func (x *x11Conn) findWindow(pids []int, classes []string) (uint32, error) {
+	names := []string{"_NET_CLIENT_LIST_STACKING", "_NET_CLIENT_LIST", "_NET_WM_PID", "WM_CLASS"}
+	atoms := make([]uint32, len(names))
+	for i, name := range names {
+		a, err := x.atom(name)
+		if err != nil {
+			return 0, err
+		}
+		atoms[i] = a
+	}
+	stacking, clientList, wmPID, wmClass := atoms[0], atoms[1], atoms[2], atoms[3]
+
+	// Atoms are interned only if they exist, so a missing one is 0.
+	var list []byte
+	for _, prop := range []uint32{stacking, clientList} {
+		if prop == 0 {
+			continue
+		}
+		var err error
+		if list, err = x.property(x.root, prop); err != nil {
+			return 0, err
+		}
+		if len(list) > 0 {
+			break
+		}
+	}
+	if len(list) == 0 {
+		return 0, fmt.Errorf("window manager does not support EWMH")
+	}
+
+	// The stacking list is ordered bottom to top; prefer the topmost window.
+	for i := len(list)/4 - 1; i >= 0; i-- {
+		win := binary.LittleEndian.Uint32(list[i*4:])
+		if wmPID != 0 && len(pids) > 0 {
+			if pid, err := x.property(win, wmPID); err == nil && len(pid) == 4 &&
+				slices.Contains(pids, int(binary.LittleEndian.Uint32(pid))) {
+				return win, nil
+			}
+		}
+		if wmClass != 0 && len(classes) > 0 {
+			if class, err := x.property(win, wmClass); err == nil {
+				for _, c := range bytes.Split(bytes.TrimRight(class, "\x00"), []byte{0}) {
+					for _, want := range classes {
+						if want != "" && strings.EqualFold(string(c), want) {
+							return win, nil
+						}
+					}
+				}
+			}
+		}
+	}
+	return 0, ErrNoWindow
+}
+
+// activate sends the _NET_ACTIVE_WINDOW client message for win to the root
+// window, as a pager would, and waits until the server has processed it.
+func (x *x11Conn) activate(win uint32) error {
+	active, err := x.atom("_NET_ACTIVE_WINDOW")
+	if err != nil {
+		return err
+	}
+
+	event := make([]byte, 32)
+	event[0], event[1] = x11ClientMessage, 32
+	binary.LittleEndian.PutUint32(event[4:], win)
+	binary.LittleEndian.PutUint32(event[8:], active)
+	binary.LittleEndian.PutUint32(event[12:], 2) // source indication: pager
+
+	body := make([]byte, 8, 40)
+	binary.LittleEndian.PutUint32(body[0:], x.root)
+	binary.LittleEndian.PutUint32(body[4:], x11SubstructureNotifyMask|x11SubstructureRedirectMask)
+	body = append(body, event...)
+	if _, err := x.request(x11SendEvent, 0, body, false); err != nil {
+		return err
+	}
+
+	_, err = x.request(x11GetInputFocus, 0, nil, true)
+	return err
+}
+
+// ActiveWindow is the WM_CLASS and title of the focused window.
+type ActiveWindow struct {
+	Instance string
+	Class    string
+	Title    string
+}
+
+// The connection ActiveWindowInfo reuses, since it runs on every copy.
+var (
+	activeMu   sync.Mutex
+	activeConn *x11Conn
+)
+
+// ActiveWindowInfo returns the window the window manager reports as active
+// in _NET_ACTIVE_WINDOW.
+func ActiveWindowInfo() (ActiveWindow, error) {
+	if runtime.GOOS != "linux" {
+		return ActiveWindow{}, fmt.Errorf("active windows are not supported on %s", runtime.GOOS)
+	}
+	activeMu.Lock()
+	defer activeMu.Unlock()
+
+	if activeConn == nil {
+		x, err := dialX11()
+		if err != nil {
+			return ActiveWindow{}, err
+		}
+		activeConn = x
+	}
+	info, err := activeConn.activeWindow()
+	if err != nil && !errors.Is(err, ErrNoWindow) {
+		// Dial again next time, the server may have gone away.
+		activeConn.conn.Close()
+		activeConn = nil
+	}
+	return info, err
+}
+
+func (x *x11Conn) activeWindow() (ActiveWindow, error) {
+	names := []string{"_NET_ACTIVE_WINDOW", "WM_CLASS", "_NET_WM_NAME", "WM_NAME"}
+	atoms := make([]uint32, len(names))
+	for i, name := range names {
+		a, err := x.atom(name)
+		if err != nil {
+			return ActiveWindow{}, err
+		}
+		atoms[i] = a
+	}
+	active, wmClass, netWMName, wmName := atoms[0], atoms[1], atoms[2], atoms[3]
+	if active == 0 {
+		return ActiveWindow{}, fmt.Errorf("window manager does not support EWMH")
+	}
+
+	value, err := x.property(x.root, active)
+	if err != nil {
+		return ActiveWindow{}, err
+	}
+	if len(value) < 4 || binary.LittleEndian.Uint32(value) == 0 {
+		return ActiveWindow{}, ErrNoWindow
+	}
+	win := binary.LittleEndian.Uint32(value)
+
+	var info ActiveWindow
+	if wmClass != 0 {
+		if class, err := x.property(win, wmClass); err == nil {
+			parts := bytes.Split(bytes.TrimRight(class, "\x00"), []byte{0})
+			info.Instance = string(parts[0])
+			if len(parts) > 1 {
+				info.Class = string(parts[1])
+			}
+		}
+	}
+	for _, prop := range []uint32{netWMName, wmName} {
+		if prop == 0 {
+			continue
+		}
+		if title, err := x.property(win, prop); err == nil && len(title) > 0 {
+			info.Title = string(title)
+			break
+		}
+	}
+	return info, nil
+}